@@ -0,0 +1,61 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/unkeep/alfabooker/export"
+)
+
+// fileResponse is a route response written as is instead of JSON, files without a name are shown inline
+type fileResponse struct {
+	contentType string
+	name        string
+	data        []byte
+}
+
+func (h *handler) v1Export(request *http.Request) (interface{}, error) {
+	query := request.URL.Query()
+
+	format := export.FormatCSV
+	if s := query.Get("format"); s != "" {
+		f, ok := export.ParseFormat(s)
+		if !ok {
+			return nil, badRequest("format must be one of: %s", formatNames())
+		}
+		format = f
+	}
+
+	from, to, err := export.ParseRange(query.Get("from"), query.Get("to"))
+	if err != nil {
+		return nil, badRequest("%s", err.Error())
+	}
+
+	txs, err := h.budgetDomain.GetTransactions(request.Context(), from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := export.RangeOptions(from, to, txs)
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, format, txs, opts); err != nil {
+		return nil, fmt.Errorf("export.Write: %w", err)
+	}
+
+	return &fileResponse{
+		contentType: format.ContentType(),
+		name:        export.FileName(format, opts.From, opts.To),
+		data:        buf.Bytes(),
+	}, nil
+}
+
+func formatNames() string {
+	names := make([]string, 0, len(export.Formats))
+	for _, f := range export.Formats {
+		names = append(names, string(f))
+	}
+	return strings.Join(names, ", ")
+}
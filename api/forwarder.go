@@ -0,0 +1,111 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// maxSMSBodySize limits a body of a single forwarded SMS
+const maxSMSBodySize = 64 << 10
+
+// Payload fields of SMS forwarder apps, Tasker and MacroDroid HTTP actions and iOS Shortcuts in priority order,
+// they are compared case-insensitively
+var (
+	smsTextFields   = []string{"sms", "text", "message", "msg", "body", "content"}
+	smsSenderFields = []string{"sender", "from", "address", "phone", "number"}
+	smsTimeFields   = []string{"timestamp", "receivedstamp", "received_at", "receivedat", "receive_time", "time", "date", "sentstamp"}
+)
+
+// decodeForwardedSMS decodes an SMS posted as JSON, a form or plain text, with withQuery URL query parameters
+// fill missing fields, so a plain text body can pass the sender as ?sender=. The query is not signed,
+// so it is ignored for signed requests.
+func decodeForwardedSMS(request *http.Request, withQuery bool) (budget.SMS, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, request.Body, maxSMSBodySize))
+	if err != nil {
+		return budget.SMS{}, badRequest("read body: %s", err.Error())
+	}
+
+	fields := map[string]interface{}{}
+	mediaType, _, _ := mime.ParseMediaType(request.Header.Get("Content-Type"))
+	switch {
+	case mediaType == "application/x-www-form-urlencoded":
+		form, err := url.ParseQuery(string(body))
+		if err != nil {
+			return budget.SMS{}, badRequest("invalid form: %s", err.Error())
+		}
+		addValues(fields, form)
+	case mediaType == "text/plain", mediaType == "" && !bytes.HasPrefix(bytes.TrimSpace(body), []byte("{")):
+		fields["sms"] = string(body)
+	default:
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return budget.SMS{}, badRequest("invalid request body: %s", err.Error())
+		}
+	}
+	if withQuery {
+		addValues(fields, request.URL.Query())
+	}
+
+	sms, err := smsFromFields(fields)
+	if err != nil {
+		return sms, err
+	}
+	if strings.TrimSpace(sms.Text) == "" {
+		return sms, badRequest("sms is required")
+	}
+
+	return sms, nil
+}
+
+// addValues adds the first values of form fields missing in fields
+func addValues(fields map[string]interface{}, values url.Values) {
+	for k := range values {
+		if _, ok := lookupField(fields, k); !ok {
+			fields[k] = values.Get(k)
+		}
+	}
+}
+
+// smsFromFields builds an SMS from payload fields, the timestamp is unix time in seconds or milliseconds
+// or an RFC 3339 string
+func smsFromFields(fields map[string]interface{}) (budget.SMS, error) {
+	var sms budget.SMS
+	sms.Text, _ = firstField(fields, smsTextFields).(string)
+	sms.Sender, _ = firstField(fields, smsSenderFields).(string)
+
+	receivedAt, err := parseTimestamp(firstField(fields, smsTimeFields))
+	if err != nil {
+		return sms, badRequest("%s", err.Error())
+	}
+	sms.ReceivedAt = receivedAt
+
+	return sms, nil
+}
+
+// firstField returns the value of the first present field of names, nil if none is present
+func firstField(fields map[string]interface{}, names []string) interface{} {
+	for _, name := range names {
+		if v, ok := lookupField(fields, name); ok && v != "" {
+			return v
+		}
+	}
+	return nil
+}
+
+func lookupField(fields map[string]interface{}, name string) (interface{}, bool) {
+	if v, ok := fields[name]; ok {
+		return v, true
+	}
+	for k, v := range fields {
+		if strings.EqualFold(k, name) {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+func TestDecodeForwardedSMS(t *testing.T) {
+	const text = "1.00 GEL\nBalance: 10.00 GEL"
+
+	tests := []struct {
+		name        string
+		target      string
+		contentType string
+		body        string
+		want        budget.SMS
+	}{
+		{
+			name:        "legacy",
+			contentType: "application/json",
+			body:        `{"sms": "1.00 GEL\nBalance: 10.00 GEL", "timestamp": 1700000000}`,
+			want:        budget.SMS{Text: text, ReceivedAt: 1700000000},
+		},
+		{
+			name:        "android forwarder",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"from": "BANK", "text": "1.00 GEL\nBalance: 10.00 GEL", "sentStamp": 1699999990000, "receivedStamp": 1700000000000, "sim": "SIM1"}`,
+			want:        budget.SMS{Text: text, Sender: "BANK", ReceivedAt: 1700000000},
+		},
+		{
+			name:        "form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "from=BANK&content=1.00+GEL%0ABalance%3A+10.00+GEL&timestamp=1700000000000",
+			want:        budget.SMS{Text: text, Sender: "BANK", ReceivedAt: 1700000000},
+		},
+		{
+			name:        "shortcuts",
+			contentType: "application/json",
+			body:        `{"Message": "1.00 GEL\nBalance: 10.00 GEL", "Sender": "BANK", "Date": "2023-11-14T22:13:20Z"}`,
+			want:        budget.SMS{Text: text, Sender: "BANK", ReceivedAt: 1700000000},
+		},
+		{
+			name:        "plain text",
+			target:      "/?sender=BANK&timestamp=1700000000",
+			contentType: "text/plain",
+			body:        text,
+			want:        budget.SMS{Text: text, Sender: "BANK", ReceivedAt: 1700000000},
+		},
+		{
+			name: "no content type",
+			body: text,
+			want: budget.SMS{Text: text},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := tt.target
+			if target == "" {
+				target = "/"
+			}
+			req := httptest.NewRequest(http.MethodPost, target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+
+			sms, err := decodeForwardedSMS(req, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sms != tt.want {
+				t.Errorf("unexpected SMS %+v", sms)
+			}
+		})
+	}
+
+	t.Run("no text", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"from": "BANK"}`))
+		req.Header.Set("Content-Type", "application/json")
+		if _, err := decodeForwardedSMS(req, true); err == nil {
+			t.Error("expected an error")
+		}
+	})
+
+	t.Run("signed query", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/?sender=EVIL&timestamp=1700000000", strings.NewReader(text))
+		req.Header.Set("Content-Type", "text/plain")
+		sms, err := decodeForwardedSMS(req, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := (budget.SMS{Text: text}); sms != want {
+			t.Errorf("query overrides a signed body: %+v", sms)
+		}
+	})
+}
@@ -2,19 +2,26 @@ package api
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
 
+	"github.com/unkeep/alfabooker/auth"
 	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/widget"
 )
 
 var PathPrefix = "/api"
 
 type handler struct {
-	authToken    string
+	auth         *auth.Service
 	budgetDomain *budget.Domain
+	smsVerifier  *SMSVerifier
+	stream       *broadcaster
+	// streamsClosed is closed when event streams have to be closed
+	streamsClosed <-chan struct{}
 }
 
 func (h *handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
@@ -31,23 +38,39 @@ func (h *handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
 		return
 	}
 
+	if strings.HasPrefix(path, v1Prefix+"/") {
+		h.serveV1(strings.TrimPrefix(path, v1Prefix), writer, request)
+		return
+	}
+
 	if request.Method == "GET" && path == "/progress_csv" {
 		h.progressCSV(request, writer)
 		return
 	}
 
-	token := request.Header.Get("Auth-Token")
-	if token != h.authToken {
+	principal, err := h.auth.Authenticate(request.Context(), request.Header.Get("Auth-Token"))
+	if err != nil {
+		if err != auth.ErrUnauthorized {
+			log.Println("auth.Authenticate:", err)
+		}
 		writer.WriteHeader(http.StatusUnauthorized)
 		return
 	}
 
 	if request.Method == "GET" && path == "/budget_stat" {
+		if !principal.Can(auth.ScopeStatsRead) {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
 		h.showBudgetStat(request, writer)
 		return
 	}
 
 	if request.Method == "POST" && path == "/account" {
+		if !principal.Can(auth.ScopeSMSWrite) {
+			writer.WriteHeader(http.StatusForbidden)
+			return
+		}
 		h.updateAccount(request, writer)
 		return
 	}
@@ -64,7 +87,13 @@ func (h *handler) showBudgetStat(request *http.Request, writer http.ResponseWrit
 		return
 	}
 
-	_ = json.NewEncoder(writer).Encode(stat)
+	body, err := json.Marshal(stat)
+	if err != nil {
+		log.Println("json.Marshal:", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	_, _ = writer.Write(body)
 }
 
 func (h *handler) progressCSV(request *http.Request, writer http.ResponseWriter) {
@@ -76,38 +105,73 @@ func (h *handler) progressCSV(request *http.Request, writer http.ResponseWriter)
 		return
 	}
 
-	balancePct := int64(stat.TotalBalance / stat.BudgetAmount * 100.0)
-
-	totalTime := stat.BudgetExpiresAt - stat.BudgetStartedAt
-	timeElapsed := time.Now().Unix() - stat.BudgetStartedAt
-
-	timeElapsedPct := int(float64(timeElapsed) / float64(totalTime) * 100.0)
-
 	writer.Header().Set("Content-Type", "text/csv")
-	_, _ = fmt.Fprintf(writer, "balance,elapsed\n")
-	_, _ = fmt.Fprintf(writer, "%d,%d\n", balancePct, timeElapsedPct)
+	_ = widget.WriteCSV(writer, widget.FromStat(*stat, time.Now()))
 }
 
 func (h *handler) updateAccount(request *http.Request, writer http.ResponseWriter) {
-	var reqData struct {
-		Sms       string      `json:"sms"`
-		Timestamp interface{} `json:"timestamp"`
+	if err := h.verifySMSSignature(request, maxSignedBodySize); err != nil {
+		writeJSONError(writer, err)
+		return
 	}
 
-	if err := json.NewDecoder(request.Body).Decode(&reqData); err != nil {
+	sms, err := decodeForwardedSMS(request, h.smsVerifier == nil)
+	if err != nil {
 		writer.WriteHeader(http.StatusBadRequest)
 		writer.Write([]byte(err.Error()))
 		return
 	}
 
-	fmt.Println("reqData.Timestamp: ", reqData.Timestamp)
-
-	err := h.budgetDomain.UpdateAccountBalanceFromSMS(request.Context(), reqData.Sms)
-	if err != nil {
-		writer.WriteHeader(http.StatusBadRequest)
-		writer.Write([]byte(err.Error()))
+	if _, err := h.budgetDomain.ApplySMS(request.Context(), sms); err != nil {
+		if errors.Is(err, budget.ErrUnrecognizedSMS) {
+			writer.WriteHeader(http.StatusBadRequest)
+			writer.Write([]byte(err.Error()))
+			return
+		}
+		// forwarders retry server errors, so the SMS is not lost during an outage
+		log.Println("budgetDomain.ApplySMS:", err)
+		writer.WriteHeader(http.StatusInternalServerError)
 		return
 	}
 
 	writer.WriteHeader(http.StatusOK)
 }
+
+// verifySMSSignature verifies the signature of an SMS ingestion request with a body of at most maxSize bytes
+// if signing is enabled
+func (h *handler) verifySMSSignature(request *http.Request, maxSize int64) error {
+	if h.smsVerifier == nil {
+		return nil
+	}
+
+	return h.smsVerifier.verify(request, maxSize)
+}
+
+// legacyPaths are paths of the unversioned API
+var legacyPaths = []string{"/", "/budget_stat", "/account", "/progress_csv"}
+
+// RouteLabel returns a route of the request path to be used as a metric label, "other" for unknown paths
+func RouteLabel(path string) string {
+	path = strings.TrimPrefix(path, PathPrefix)
+
+	if strings.HasPrefix(path, v1Prefix+"/") {
+		route := strings.TrimPrefix(path, v1Prefix)
+		if route == "/openapi.json" || route == streamPath {
+			return PathPrefix + path
+		}
+		for _, r := range v1Routes {
+			if r.path == route {
+				return PathPrefix + path
+			}
+		}
+		return "other"
+	}
+
+	for _, p := range legacyPaths {
+		if p == path {
+			return PathPrefix + path
+		}
+	}
+
+	return "other"
+}
@@ -1,23 +1,28 @@
 package api
 
 import (
-	"crypto/tls"
+	"context"
 	"net/http"
 
+	"github.com/unkeep/alfabooker/auth"
 	"github.com/unkeep/alfabooker/budget"
 )
 
-func NewServer(port string, budgetDomain *budget.Domain, authToken string) http.Server {
-	http.DefaultTransport.(*http.Transport).TLSClientConfig = &tls.Config{InsecureSkipVerify: true}
-
-	h := &handler{budgetDomain: budgetDomain, authToken: authToken}
+// NewHandler creates the API handler, SMS requests are not required to be signed if smsVerifier is nil,
+// event streams are closed when ctx is done, so they do not hold a server shutdown
+func NewHandler(ctx context.Context, budgetDomain *budget.Domain, authService *auth.Service, smsVerifier *SMSVerifier) http.Handler {
+	return newHandler(ctx, budgetDomain, authService, smsVerifier)
+}
 
-	return http.Server{
-		Addr:    "0.0.0.0:" + port,
-		Handler: h,
+func newHandler(ctx context.Context, budgetDomain *budget.Domain, authService *auth.Service, smsVerifier *SMSVerifier) *handler {
+	h := &handler{
+		budgetDomain:  budgetDomain,
+		auth:          authService,
+		smsVerifier:   smsVerifier,
+		stream:        newBroadcaster(),
+		streamsClosed: ctx.Done(),
 	}
-}
+	budgetDomain.Subscribe(h.onBudgetEvent)
 
-func NewHandler(budgetDomain *budget.Domain, authToken string) http.Handler {
-	return &handler{budgetDomain: budgetDomain, authToken: authToken}
+	return h
 }
@@ -0,0 +1,103 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Headers of signed SMS requests
+const (
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+// maxSignedBodySize limits a body of a single SMS read into memory to verify its signature
+const maxSignedBodySize = 1 << 20
+
+// maxNonceLen keeps nonces from bloating the nonce store
+const maxNonceLen = 128
+
+// NonceStore remembers used nonces
+type NonceStore interface {
+	// Remember stores the nonce until expiresAt and reports whether it has been already used
+	Remember(ctx context.Context, nonce string, expiresAt time.Time) (bool, error)
+}
+
+// SMSVerifier verifies signatures of SMS ingestion requests
+type SMSVerifier struct {
+	secret []byte
+	skew   time.Duration
+	nonces NonceStore
+	now    func() time.Time
+}
+
+// NewSMSVerifier creates a verifier of requests signed with the shared secret,
+// requests with a timestamp differing from the server time by more than skew are rejected
+func NewSMSVerifier(secret string, skew time.Duration, nonces NonceStore) *SMSVerifier {
+	return &SMSVerifier{secret: []byte(secret), skew: skew, nonces: nonces, now: time.Now}
+}
+
+// SignSMSRequest returns a signature of the request body, a forwarder sends it in the X-Signature header
+func SignSMSRequest(secret string, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "%d\n%s\n", timestamp, nonce)
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// verify checks the signature of the request with a body of at most maxSize bytes, the body stays readable
+func (v *SMSVerifier) verify(request *http.Request, maxSize int64) error {
+	body, err := io.ReadAll(io.LimitReader(request.Body, maxSize+1))
+	if err != nil {
+		return badRequest("read body: %s", err.Error())
+	}
+	if int64(len(body)) > maxSize {
+		return badRequest("body is larger than %d bytes", maxSize)
+	}
+	request.Body = io.NopCloser(bytes.NewReader(body))
+
+	timestamp, err := strconv.ParseInt(request.Header.Get(HeaderTimestamp), 10, 64)
+	if err != nil {
+		return invalidSignature("%s header must be unix time in seconds", HeaderTimestamp)
+	}
+
+	nonce := request.Header.Get(HeaderNonce)
+	if nonce == "" || len(nonce) > maxNonceLen {
+		return invalidSignature("%s header is required and must be at most %d bytes", HeaderNonce, maxNonceLen)
+	}
+
+	expected := SignSMSRequest(string(v.secret), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(request.Header.Get(HeaderSignature))) {
+		return invalidSignature("signature mismatch")
+	}
+
+	signedAt := time.Unix(timestamp, 0)
+	now := v.now()
+	if signedAt.Before(now.Add(-v.skew)) || signedAt.After(now.Add(v.skew)) {
+		return invalidSignature("timestamp is out of the allowed %s window", v.skew)
+	}
+
+	// a nonce can not be replayed after the window anyway
+	seen, err := v.nonces.Remember(request.Context(), nonce, signedAt.Add(v.skew))
+	if err != nil {
+		return fmt.Errorf("NonceStore.Remember: %w", err)
+	}
+	if seen {
+		return invalidSignature("nonce has been already used")
+	}
+
+	return nil
+}
+
+func invalidSignature(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusUnauthorized, code: "invalid_signature", message: fmt.Sprintf(format, args...)}
+}
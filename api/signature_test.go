@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type memNonces map[string]bool
+
+func (m memNonces) Remember(_ context.Context, nonce string, _ time.Time) (bool, error) {
+	seen := m[nonce]
+	m[nonce] = true
+	return seen, nil
+}
+
+func TestSMSVerifier(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	v := NewSMSVerifier("secret", time.Minute, memNonces{})
+	v.now = func() time.Time { return now }
+
+	const body = `{"sms": "Balance: 100.00 GEL"}`
+	newRequest := func(ts int64, nonce, signature string) *http.Request {
+		r := httptest.NewRequest(http.MethodPost, "/api/v1/account/sms", strings.NewReader(body))
+		r.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+		r.Header.Set(HeaderNonce, nonce)
+		r.Header.Set(HeaderSignature, signature)
+		return r
+	}
+	sign := func(ts int64, nonce string) string {
+		return SignSMSRequest("secret", ts, nonce, []byte(body))
+	}
+
+	ts := now.Unix()
+	tests := []struct {
+		name  string
+		req   *http.Request
+		valid bool
+	}{
+		{"valid", newRequest(ts, "n1", sign(ts, "n1")), true},
+		{"replay", newRequest(ts, "n1", sign(ts, "n1")), false},
+		{"wrong secret", newRequest(ts, "n2", SignSMSRequest("other", ts, "n2", []byte(body))), false},
+		{"tampered nonce", newRequest(ts, "n3", sign(ts, "n2")), false},
+		{"no nonce", newRequest(ts, "", sign(ts, "")), false},
+		{"within skew", newRequest(ts-50, "n4", sign(ts-50, "n4")), true},
+		{"stale", newRequest(ts-61, "n5", sign(ts-61, "n5")), false},
+		{"future", newRequest(ts+61, "n6", sign(ts+61, "n6")), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.verify(tt.req, maxSignedBodySize)
+			if tt.valid && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !tt.valid && err == nil {
+				t.Fatal("expected an error")
+			}
+
+			if data, _ := io.ReadAll(tt.req.Body); string(data) != body {
+				t.Errorf("body is not restored: %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// maxImportBodySize limits the size of an SMS export
+const maxImportBodySize = 32 << 20
+
+// maxSMSLineSize limits a single NDJSON line
+const maxSMSLineSize = 64 << 10
+
+// msTimestampThreshold separates timestamps in seconds from ones in milliseconds
+const msTimestampThreshold = 1e12
+
+// smsBackup is an export of the "SMS Backup & Restore" Android app
+type smsBackup struct {
+	Messages []struct {
+		Address string `xml:"address,attr"`
+		// Date is unix time in milliseconds
+		Date int64  `xml:"date,attr"`
+		Body string `xml:"body,attr"`
+	} `xml:"sms"`
+}
+
+func (h *handler) v1ImportSMS(request *http.Request) (interface{}, error) {
+	// an unsigned batch could move the balance as well as a single SMS
+	if err := h.verifySMSSignature(request, maxImportBodySize); err != nil {
+		return nil, err
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(nil, request.Body, maxImportBodySize))
+	if err != nil {
+		return nil, badRequest("read body: %s", err.Error())
+	}
+
+	var msgs []budget.SMS
+	var lineErrs map[int]error
+	if strings.Contains(request.Header.Get("Content-Type"), "xml") {
+		msgs, err = decodeSMSBackup(body)
+	} else {
+		msgs, lineErrs, err = decodeSMSLines(body)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	report, err := h.budgetDomain.ImportSMS(request.Context(), msgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// lines which are not even JSON are reported with the decoding error
+	for i, err := range lineErrs {
+		report.Entries[i].Error = err.Error()
+	}
+
+	return report, nil
+}
+
+func decodeSMSBackup(body []byte) ([]budget.SMS, error) {
+	var backup smsBackup
+	if err := xml.Unmarshal(body, &backup); err != nil {
+		return nil, badRequest("invalid SMS backup: %s", err.Error())
+	}
+
+	msgs := make([]budget.SMS, 0, len(backup.Messages))
+	for _, m := range backup.Messages {
+		msgs = append(msgs, budget.SMS{Text: m.Body, Sender: m.Address, ReceivedAt: m.Date / 1000})
+	}
+
+	return msgs, nil
+}
+
+// decodeSMSLines decodes NDJSON lines like {"sms": "...", "timestamp": 1700000000} or with fields of forwarder apps,
+// messages of malformed lines are left empty
+func decodeSMSLines(body []byte) ([]budget.SMS, map[int]error, error) {
+	var msgs []budget.SMS
+	lineErrs := map[int]error{}
+
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(nil, maxSMSLineSize)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var fields map[string]interface{}
+		err := json.Unmarshal(line, &fields)
+		if err != nil {
+			lineErrs[len(msgs)] = fmt.Errorf("invalid JSON: %w", err)
+			msgs = append(msgs, budget.SMS{})
+			continue
+		}
+
+		sms, err := smsFromFields(fields)
+		if err != nil {
+			lineErrs[len(msgs)] = err
+			msgs = append(msgs, budget.SMS{})
+			continue
+		}
+
+		msgs = append(msgs, sms)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, nil, badRequest("read lines: %s", err.Error())
+	}
+
+	return msgs, lineErrs, nil
+}
+
+// parseTimestamp parses unix time in seconds or milliseconds, also as a string, or an RFC 3339 string,
+// nil is zero time
+func parseTimestamp(v interface{}) (int64, error) {
+	switch ts := v.(type) {
+	case nil:
+		return 0, nil
+	case float64:
+		if ts > msTimestampThreshold {
+			return int64(ts / 1000), nil
+		}
+		return int64(ts), nil
+	case string:
+		// forms and templates of forwarder apps pass numbers as strings
+		if n, err := strconv.ParseFloat(ts, 64); err == nil {
+			return parseTimestamp(n)
+		}
+		t, err := time.Parse(time.RFC3339, ts)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp: %w", err)
+		}
+		return t.Unix(), nil
+	}
+
+	return 0, fmt.Errorf("invalid timestamp: %v", v)
+}
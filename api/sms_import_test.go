@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unkeep/alfabooker/auth"
+)
+
+func TestDecodeSMSLines(t *testing.T) {
+	body := `{"sms": "1.00 GEL\nBalance: 10.00 GEL", "timestamp": 1700000000000}
+
+{"sms": "2.00 GEL\nBalance: 8.00 GEL", "timestamp": "2023-11-20T10:00:00Z"}
+not json
+{"sms": "3.00 GEL\nBalance: 5.00 GEL", "timestamp": true}
+`
+	msgs, lineErrs, err := decodeSMSLines([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 4 {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+	if msgs[0].ReceivedAt != 1700000000 || msgs[1].ReceivedAt != 1700474400 {
+		t.Errorf("unexpected timestamps %d, %d", msgs[0].ReceivedAt, msgs[1].ReceivedAt)
+	}
+	if len(lineErrs) != 2 || lineErrs[2] == nil || lineErrs[3] == nil {
+		t.Errorf("unexpected line errors %v", lineErrs)
+	}
+}
+
+func TestDecodeSMSBackup(t *testing.T) {
+	body := `<?xml version='1.0' encoding='UTF-8' standalone='yes' ?>
+<smses count="2">
+  <sms protocol="0" address="ALFA" date="1700000000000" type="1" body="1.00 GEL&#10;Balance: 10.00 GEL" />
+  <sms protocol="0" address="ALFA" date="1700000100000" type="1" body="2.00 GEL&#10;Balance: 8.00 GEL" />
+</smses>`
+	msgs, err := decodeSMSBackup([]byte(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(msgs) != 2 || msgs[1].ReceivedAt != 1700000100 || msgs[0].Text != "1.00 GEL\nBalance: 10.00 GEL" {
+		t.Errorf("unexpected messages %+v", msgs)
+	}
+}
+
+func TestImportSMSUnsigned(t *testing.T) {
+	h := &handler{
+		auth:        auth.NewService(nil, "token"),
+		smsVerifier: NewSMSVerifier("secret", time.Minute, memNonces{}),
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/sms/import", strings.NewReader(`{"sms": "1.00 GEL\nBalance: 10.00 GEL"}`))
+	req.Header.Set("Auth-Token", "token")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), "invalid_signature") {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
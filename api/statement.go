@@ -0,0 +1,30 @@
+package api
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/unkeep/alfabooker/statement"
+)
+
+func (h *handler) v1ImportStatement(request *http.Request) (interface{}, error) {
+	data, err := io.ReadAll(http.MaxBytesReader(nil, request.Body, maxImportBodySize))
+	if err != nil {
+		return nil, badRequest("read body: %s", err.Error())
+	}
+
+	format, ok := statement.DetectFormat(request.URL.Query().Get("name"), data)
+	if s := request.URL.Query().Get("format"); s != "" {
+		format, ok = statement.ParseFormat(s)
+	}
+	if !ok {
+		return nil, badRequest("unknown statement format, use csv or ofx")
+	}
+
+	rows, err := statement.Parse(format, data)
+	if err != nil {
+		return nil, badRequest("invalid statement: %s", err.Error())
+	}
+
+	return h.budgetDomain.ReconcileStatement(request.Context(), rows)
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// streamPath is a path of the budget updates stream relative to v1Prefix
+const streamPath = "/stream"
+
+// streamHeartbeat is an interval of keep-alive comments preventing proxies from closing idle streams
+var streamHeartbeat = 15 * time.Second
+
+// streamBuffer is a number of messages buffered for a slow client before they are dropped
+const streamBuffer = 8
+
+// snapshotEvent is an event name of the statistics sent on connect
+const snapshotEvent = "snapshot"
+
+// streamMessage is a budget update pushed to stream clients
+type streamMessage struct {
+	Event *budget.Event      `json:"event,omitempty"`
+	Stat  *budget.Statistics `json:"stat"`
+}
+
+// broadcaster fans budget updates out to connected stream clients
+type broadcaster struct {
+	mu   sync.Mutex
+	subs map[chan streamMessage]struct{}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subs: map[chan streamMessage]struct{}{}}
+}
+
+// subscribe returns a channel of messages and a function to unsubscribe
+func (b *broadcaster) subscribe() (<-chan streamMessage, func()) {
+	ch := make(chan streamMessage, streamBuffer)
+
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+func (b *broadcaster) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subs)
+}
+
+// publish sends the message to all clients, messages are dropped for clients not keeping up
+func (b *broadcaster) publish(m streamMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subs {
+		select {
+		case ch <- m:
+		default:
+			log.Println("stream: dropped a message for a slow client")
+		}
+	}
+}
+
+// onBudgetEvent publishes fresh statistics of a budget change
+func (h *handler) onBudgetEvent(ctx context.Context, e budget.Event) {
+	if h.stream.count() == 0 {
+		return
+	}
+
+	stat, err := h.budgetDomain.GetStat(ctx)
+	if err != nil {
+		log.Println("budgetDomain.GetStat:", err)
+		return
+	}
+
+	// reconciliation details are not a part of live updates
+	e.Reconciliation = nil
+	h.stream.publish(streamMessage{Event: &e, Stat: stat})
+}
+
+// v1Stream streams statistics as server-sent events on every budget change
+func (h *handler) v1Stream(writer http.ResponseWriter, request *http.Request) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		writeJSONError(writer, fmt.Errorf("streaming is not supported"))
+		return
+	}
+
+	msgs, unsubscribe := h.stream.subscribe()
+	defer unsubscribe()
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("X-Accel-Buffering", "no")
+	writer.WriteHeader(http.StatusOK)
+
+	stat, err := h.budgetDomain.GetStat(request.Context())
+	if err == nil {
+		writeEvent(writer, snapshotEvent, streamMessage{Stat: stat})
+	} else {
+		log.Println("budgetDomain.GetStat:", err)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-h.streamsClosed:
+			return
+		case m := <-msgs:
+			writeEvent(writer, string(m.Event.Type), m)
+		case <-heartbeat.C:
+			_, _ = fmt.Fprint(writer, ": ping\n\n")
+		}
+		flusher.Flush()
+	}
+}
+
+func writeEvent(writer http.ResponseWriter, name string, m streamMessage) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		log.Println("json.Marshal:", err)
+		return
+	}
+
+	_, _ = fmt.Fprintf(writer, "event: %s\ndata: %s\n\n", name, data)
+}
@@ -0,0 +1,33 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+func TestBroadcaster(t *testing.T) {
+	b := newBroadcaster()
+
+	fast, unsubscribeFast := b.subscribe()
+	defer unsubscribeFast()
+	slow, unsubscribeSlow := b.subscribe()
+
+	for i := 0; i < streamBuffer+2; i++ {
+		b.publish(streamMessage{Event: &budget.Event{Type: budget.EventCashUpdated}, Stat: &budget.Statistics{TotalBalance: float64(i)}})
+		<-fast
+	}
+
+	// the slow client gets the buffered messages, the rest are dropped instead of blocking others
+	if len(slow) != streamBuffer {
+		t.Errorf("unexpected buffered messages %d", len(slow))
+	}
+	if m := <-slow; m.Stat.TotalBalance != 0 {
+		t.Errorf("unexpected first message %+v", m.Stat)
+	}
+
+	unsubscribeSlow()
+	if b.count() != 1 {
+		t.Errorf("unexpected subscribers %d", b.count())
+	}
+}
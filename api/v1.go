@@ -0,0 +1,402 @@
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/unkeep/alfabooker/auth"
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/db"
+)
+
+// v1Prefix is a path prefix of the versioned API relative to PathPrefix
+const v1Prefix = "/v1"
+
+//go:embed openapi.json
+var openAPIDoc []byte
+
+// apiError is an error with an HTTP status and a machine readable code
+type apiError struct {
+	status  int
+	code    string
+	message string
+}
+
+func (e *apiError) Error() string {
+	return e.message
+}
+
+func badRequest(format string, args ...interface{}) error {
+	return &apiError{status: http.StatusBadRequest, code: "bad_request", message: fmt.Sprintf(format, args...)}
+}
+
+type v1Route struct {
+	method string
+	path   string
+	scope  string
+	handle func(h *handler, r *http.Request) (interface{}, error)
+}
+
+var v1Routes = []v1Route{
+	{http.MethodGet, "/stat", auth.ScopeStatsRead, (*handler).v1GetStat},
+	{http.MethodGet, "/budget", auth.ScopeStatsRead, (*handler).v1GetBudget},
+	{http.MethodPut, "/budget", auth.ScopeBudgetWrite, (*handler).v1PutBudget},
+	{http.MethodPost, "/budget/start", auth.ScopeBudgetWrite, (*handler).v1StartBudget},
+	{http.MethodPost, "/budget/add", auth.ScopeBudgetWrite, (*handler).v1AddBudget},
+	{http.MethodPost, "/budget/align", auth.ScopeBudgetWrite, (*handler).v1AlignBudget},
+	{http.MethodGet, "/periods", auth.ScopeStatsRead, (*handler).v1GetPeriods},
+	{http.MethodPut, "/account", auth.ScopeBudgetWrite, (*handler).v1PutAccount},
+	{http.MethodPost, "/account/sms", auth.ScopeSMSWrite, (*handler).v1PostSMS},
+	{http.MethodPost, "/sms/import", auth.ScopeSMSWrite, (*handler).v1ImportSMS},
+	{http.MethodPut, "/cash", auth.ScopeBudgetWrite, (*handler).v1PutCash},
+	{http.MethodPost, "/cash/add", auth.ScopeBudgetWrite, (*handler).v1AddCash},
+	{http.MethodPut, "/reserve", auth.ScopeBudgetWrite, (*handler).v1PutReserve},
+	{http.MethodGet, "/export", auth.ScopeStatsRead, (*handler).v1Export},
+	{http.MethodPost, "/statements", auth.ScopeBudgetWrite, (*handler).v1ImportStatement},
+	{http.MethodGet, widgetPath, auth.ScopeStatsRead, (*handler).v1Widget},
+	{http.MethodGet, "/widget/formats", auth.ScopeStatsRead, (*handler).v1GetWidgetFormats},
+	{http.MethodPut, "/widget/formats", auth.ScopeWidgetsWrite, (*handler).v1PutWidgetFormat},
+	{http.MethodDelete, "/widget/formats", auth.ScopeWidgetsWrite, (*handler).v1DeleteWidgetFormat},
+}
+
+// serveV1 serves the versioned API, path is relative to v1Prefix
+func (h *handler) serveV1(path string, writer http.ResponseWriter, request *http.Request) {
+	if request.Method == http.MethodGet && path == "/openapi.json" {
+		writer.Header().Set("Content-Type", "application/json")
+		_, _ = writer.Write(openAPIDoc)
+		return
+	}
+
+	token := request.Header.Get("Auth-Token")
+	// EventSource of browsers and widgets can not set headers
+	if token == "" && (path == streamPath || path == widgetPath) {
+		token = request.URL.Query().Get("token")
+	}
+
+	principal, err := h.auth.Authenticate(request.Context(), token)
+	if err != nil {
+		if err != auth.ErrUnauthorized {
+			log.Println("auth.Authenticate:", err)
+		}
+		writeJSONError(writer, &apiError{status: http.StatusUnauthorized, code: "unauthorized", message: "invalid auth token"})
+		return
+	}
+	request = request.WithContext(context.WithValue(request.Context(), principalKey{}, principal))
+
+	if path == streamPath && request.Method == http.MethodGet {
+		if !principal.Can(auth.ScopeStatsRead) {
+			writeJSONError(writer, &apiError{status: http.StatusForbidden, code: "forbidden", message: "token has no " + auth.ScopeStatsRead + " scope"})
+			return
+		}
+		h.v1Stream(writer, request)
+		return
+	}
+
+	pathFound := false
+	for _, route := range v1Routes {
+		if route.path != path {
+			continue
+		}
+		pathFound = true
+		if route.method != request.Method {
+			continue
+		}
+
+		if !principal.Can(route.scope) {
+			writeJSONError(writer, &apiError{status: http.StatusForbidden, code: "forbidden", message: "token has no " + route.scope + " scope"})
+			return
+		}
+
+		resp, err := route.handle(h, request)
+		if err != nil {
+			writeJSONError(writer, err)
+			return
+		}
+		if file, ok := resp.(*fileResponse); ok {
+			writer.Header().Set("Content-Type", file.contentType)
+			if file.name != "" {
+				writer.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.name))
+			} else {
+				// unnamed files are live renderings
+				writer.Header().Set("Cache-Control", "no-store")
+			}
+			_, _ = writer.Write(file.data)
+			return
+		}
+		writeJSON(writer, http.StatusOK, resp)
+		return
+	}
+
+	if pathFound {
+		writeJSONError(writer, &apiError{status: http.StatusMethodNotAllowed, code: "method_not_allowed", message: "method not allowed"})
+		return
+	}
+	writeJSONError(writer, &apiError{status: http.StatusNotFound, code: "not_found", message: "not found"})
+}
+
+// writeJSON encodes the response before writing the status, so an encoding error is still reported as 500
+func writeJSON(writer http.ResponseWriter, status int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		log.Println("json.Marshal:", err)
+		status = http.StatusInternalServerError
+		body = []byte(`{"error":{"code":"internal","message":"unable to encode the response"}}`)
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	_, _ = writer.Write(append(body, '\n'))
+}
+
+func writeJSONError(writer http.ResponseWriter, err error) {
+	var apiErr *apiError
+	if !errors.As(err, &apiErr) {
+		apiErr = &apiError{status: http.StatusInternalServerError, code: "internal", message: "internal error"}
+		if errors.Is(err, db.ErrNotFound) {
+			apiErr = &apiError{status: http.StatusNotFound, code: "not_found", message: "budget is not configured"}
+		} else {
+			// details of internal errors are not shown to clients
+			log.Println("api:", err)
+		}
+	}
+
+	var body struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	body.Error.Code = apiErr.code
+	body.Error.Message = apiErr.message
+
+	writeJSON(writer, apiErr.status, body)
+}
+
+func decodeJSON(request *http.Request, v interface{}) error {
+	dec := json.NewDecoder(request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return badRequest("invalid request body: %s", err.Error())
+	}
+	return nil
+}
+
+// valueRequest is a request body of operations taking a single amount
+type valueRequest struct {
+	Value *float64 `json:"value"`
+}
+
+func decodeValue(request *http.Request) (float64, error) {
+	var req valueRequest
+	if err := decodeJSON(request, &req); err != nil {
+		return 0, err
+	}
+	if req.Value == nil {
+		return 0, badRequest("value is required")
+	}
+	return *req.Value, nil
+}
+
+// statAfter runs a budget operation and responds with the resulting statistics
+func (h *handler) statAfter(request *http.Request, op func() error) (interface{}, error) {
+	if err := op(); err != nil {
+		return nil, err
+	}
+	return h.budgetDomain.GetStat(request.Context())
+}
+
+func (h *handler) v1GetStat(request *http.Request) (interface{}, error) {
+	return h.budgetDomain.GetStat(request.Context())
+}
+
+func (h *handler) v1GetBudget(request *http.Request) (interface{}, error) {
+	return h.budgetDomain.GetBudget(request.Context())
+}
+
+func (h *handler) v1PutBudget(request *http.Request) (interface{}, error) {
+	var req struct {
+		Amount    *float64 `json:"amount"`
+		StartedAt *int64   `json:"started_at"`
+		ExpiresAt *int64   `json:"expires_at"`
+	}
+	if err := decodeJSON(request, &req); err != nil {
+		return nil, err
+	}
+
+	b, err := h.budgetDomain.GetBudget(request.Context())
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+	if req.Amount != nil {
+		b.Amount = *req.Amount
+	}
+	if req.StartedAt != nil {
+		b.StartedAt = *req.StartedAt
+	}
+	if req.ExpiresAt != nil {
+		b.ExpiresAt = *req.ExpiresAt
+	}
+	if b.ExpiresAt <= b.StartedAt {
+		return nil, badRequest("expires_at must be after started_at")
+	}
+
+	if err := h.budgetDomain.ConfigureBudget(request.Context(), b.Amount, b.StartedAt, b.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return h.budgetDomain.GetBudget(request.Context())
+}
+
+func (h *handler) v1StartBudget(request *http.Request) (interface{}, error) {
+	var req struct {
+		Days     int      `json:"days"`
+		Amount   *float64 `json:"amount"`
+		Reserved *float64 `json:"reserved"`
+	}
+	if err := decodeJSON(request, &req); err != nil {
+		return nil, err
+	}
+	if req.Days <= 0 {
+		return nil, badRequest("days must be positive")
+	}
+
+	return h.statAfter(request, func() error {
+		if req.Amount == nil && req.Reserved == nil {
+			return h.budgetDomain.StartBudget(request.Context(), req.Days)
+		}
+
+		b, err := h.budgetDomain.GetBudget(request.Context())
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+		reserved := b.ReservedValue
+		if req.Reserved != nil {
+			reserved = *req.Reserved
+		}
+		amount := b.Balance + b.CashBalance - reserved
+		if req.Amount != nil {
+			amount = *req.Amount
+		}
+
+		return h.budgetDomain.StartCustomBudget(request.Context(), req.Days, amount, reserved)
+	})
+}
+
+func (h *handler) v1AddBudget(request *http.Request) (interface{}, error) {
+	val, err := decodeValue(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.statAfter(request, func() error {
+		return h.budgetDomain.AddBudget(request.Context(), val)
+	})
+}
+
+func (h *handler) v1AlignBudget(request *http.Request) (interface{}, error) {
+	val, err := decodeValue(request)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := h.budgetDomain.GetBudget(request.Context())
+	if err != nil {
+		return nil, err
+	}
+	if b.Amount <= 0 || val <= 0 || val >= b.Amount {
+		return nil, badRequest("value must be positive and less than the budget amount")
+	}
+
+	return h.statAfter(request, func() error {
+		return h.budgetDomain.DecreaseAndAlignBudget(request.Context(), val)
+	})
+}
+
+func (h *handler) v1GetPeriods(request *http.Request) (interface{}, error) {
+	past := 0
+	if s := request.URL.Query().Get("past"); s != "" {
+		val, err := strconv.Atoi(s)
+		if err != nil || val < 0 {
+			return nil, badRequest("past must be a non-negative integer")
+		}
+		past = val
+	}
+
+	return h.budgetDomain.GetPeriods(request.Context(), past)
+}
+
+func (h *handler) v1PutAccount(request *http.Request) (interface{}, error) {
+	var req struct {
+		Balance *float64 `json:"balance"`
+	}
+	if err := decodeJSON(request, &req); err != nil {
+		return nil, err
+	}
+	if req.Balance == nil {
+		return nil, badRequest("balance is required")
+	}
+
+	return h.statAfter(request, func() error {
+		return h.budgetDomain.UpdateAccountBalance(request.Context(), *req.Balance)
+	})
+}
+
+func (h *handler) v1PostSMS(request *http.Request) (interface{}, error) {
+	if err := h.verifySMSSignature(request, maxSignedBodySize); err != nil {
+		return nil, err
+	}
+
+	sms, err := decodeForwardedSMS(request, h.smsVerifier == nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// other errors are internal, forwarders retry them
+	if _, err := h.budgetDomain.ApplySMS(request.Context(), sms); err != nil {
+		if errors.Is(err, budget.ErrUnrecognizedSMS) {
+			return nil, badRequest("%s", err.Error())
+		}
+		return nil, fmt.Errorf("budgetDomain.ApplySMS: %w", err)
+	}
+
+	return h.budgetDomain.GetStat(request.Context())
+}
+
+func (h *handler) v1PutCash(request *http.Request) (interface{}, error) {
+	val, err := decodeValue(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.statAfter(request, func() error {
+		return h.budgetDomain.SetCash(request.Context(), val)
+	})
+}
+
+func (h *handler) v1AddCash(request *http.Request) (interface{}, error) {
+	val, err := decodeValue(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.statAfter(request, func() error {
+		return h.budgetDomain.AddCash(request.Context(), val)
+	})
+}
+
+func (h *handler) v1PutReserve(request *http.Request) (interface{}, error) {
+	val, err := decodeValue(request)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.statAfter(request, func() error {
+		return h.budgetDomain.SetReservedValue(request.Context(), val)
+	})
+}
@@ -0,0 +1,144 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/unkeep/alfabooker/auth"
+	"github.com/unkeep/alfabooker/db"
+)
+
+func TestOpenAPIDoc(t *testing.T) {
+	var doc struct {
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPIDoc, &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, route := range v1Routes {
+		if _, ok := doc.Paths[route.path][strings.ToLower(route.method)]; !ok {
+			t.Errorf("%s %s is not documented", route.method, route.path)
+		}
+	}
+}
+
+func TestV1RouteScopes(t *testing.T) {
+	for _, route := range v1Routes {
+		if route.method != http.MethodGet && route.scope == auth.ScopeStatsRead {
+			t.Errorf("%s %s changes data with a read scope", route.method, route.path)
+		}
+	}
+}
+
+func TestServeV1(t *testing.T) {
+	h := &handler{auth: auth.NewService(nil, "token")}
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		status int
+		code   string
+	}{
+		{"unauthorized", http.MethodGet, "/api/v1/stat", "", http.StatusUnauthorized, "unauthorized"},
+		{"not found", http.MethodGet, "/api/v1/unknown", "token", http.StatusNotFound, "not_found"},
+		{"method not allowed", http.MethodDelete, "/api/v1/cash", "token", http.StatusMethodNotAllowed, "method_not_allowed"},
+		{"invalid body", http.MethodPut, "/api/v1/cash", "token", http.StatusBadRequest, "bad_request"},
+		{"unknown widget format", http.MethodGet, "/api/v1/widget?format=eink&token=token", "", http.StatusNotFound, "not_found"},
+		{"built-in widget format", http.MethodPut, "/api/v1/widget/formats?name=json", "token", http.StatusBadRequest, "bad_request"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(`{"amount": 1}`))
+			req.Header.Set("Auth-Token", tt.token)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+
+			if w.Code != tt.status {
+				t.Errorf("unexpected status %d", w.Code)
+			}
+
+			var body struct {
+				Error struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Error.Code != tt.code {
+				t.Errorf("unexpected code %s", body.Error.Code)
+			}
+		})
+	}
+
+	t.Run("openapi", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/openapi.json", nil))
+
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected response %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+	})
+}
+
+func TestRouteLabel(t *testing.T) {
+	tests := map[string]string{
+		"/api/":                "/api/",
+		"/api/budget_stat":     "/api/budget_stat",
+		"/api/v1/stat":         "/api/v1/stat",
+		"/api/v1/openapi.json": "/api/v1/openapi.json",
+		"/api/v1/stat/123":     "other",
+		"/api/unknown":         "other",
+	}
+
+	for path, want := range tests {
+		if got := RouteLabel(path); got != want {
+			t.Errorf("RouteLabel(%s) = %s", path, got)
+		}
+	}
+}
+
+func TestWriteJSONEncodingError(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeJSON(w, http.StatusOK, map[string]float64{"value": math.NaN()})
+
+	if w.Code != http.StatusInternalServerError || !strings.Contains(w.Body.String(), "internal") {
+		t.Errorf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestWriteJSONError(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"bad request", badRequest("sms is required"), http.StatusBadRequest},
+		{"internal", fmt.Errorf("budgetDomain.ApplySMS: %w", errors.New("mongo: connection refused")), http.StatusInternalServerError},
+		{"not found", fmt.Errorf("BudgetRepo.Get: %w", db.ErrNotFound), http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeJSONError(w, tt.err)
+
+			if w.Code != tt.status {
+				t.Errorf("unexpected status %d", w.Code)
+			}
+			if strings.Contains(w.Body.String(), "mongo") {
+				t.Errorf("internal error is exposed: %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/unkeep/alfabooker/auth"
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/widget"
+)
+
+// widgetPath is a path of widget renderings, it accepts the token in the query like the stream
+const widgetPath = "/widget"
+
+type principalKey struct{}
+
+// principalFrom returns the principal authenticated by serveV1
+func principalFrom(ctx context.Context) auth.Principal {
+	p, _ := ctx.Value(principalKey{}).(auth.Principal)
+	return p
+}
+
+// widgetFormats is a response listing widget templates of the token
+type widgetFormats struct {
+	Formats map[string]string `json:"formats"`
+}
+
+func (h *handler) v1Widget(request *http.Request) (interface{}, error) {
+	format := request.URL.Query().Get("format")
+	if format == "" {
+		format = widget.FormatJSON
+	}
+
+	tmpl, custom := principalFrom(request.Context()).Widgets[format]
+	if !custom && !widget.IsBuiltin(format) {
+		return nil, &apiError{status: http.StatusNotFound, code: "not_found", message: "unknown widget format " + format}
+	}
+
+	stat, err := h.budgetDomain.GetStat(request.Context())
+	if err != nil {
+		return nil, err
+	}
+	progress := widget.FromStat(*stat, time.Now())
+
+	var buf bytes.Buffer
+	resp := &fileResponse{contentType: "text/plain; charset=utf-8"}
+	switch {
+	case custom:
+		if err := widget.ExecuteTemplate(&buf, tmpl, progress); err != nil {
+			return nil, badRequest("widget template: %s", err.Error())
+		}
+	case format == widget.FormatJSON:
+		return progress, nil
+	case format == widget.FormatCSV:
+		resp.contentType = "text/csv"
+		err = widget.WriteCSV(&buf, progress)
+	case format == widget.FormatBadge:
+		resp.contentType = "image/svg+xml"
+		err = widget.WriteBadge(&buf, progress)
+	case format == widget.FormatBar:
+		resp.contentType = "image/svg+xml"
+		err = widget.WriteBar(&buf, progress)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	resp.data = buf.Bytes()
+	return resp, nil
+}
+
+func (h *handler) v1GetWidgetFormats(request *http.Request) (interface{}, error) {
+	formats := principalFrom(request.Context()).Widgets
+	if formats == nil {
+		formats = map[string]string{}
+	}
+	return widgetFormats{Formats: formats}, nil
+}
+
+func (h *handler) v1PutWidgetFormat(request *http.Request) (interface{}, error) {
+	name := request.URL.Query().Get("name")
+	if err := widget.ValidateName(name); err != nil {
+		return nil, badRequest("%s", err.Error())
+	}
+
+	body, err := io.ReadAll(io.LimitReader(request.Body, widget.MaxTemplateSize+1))
+	if err != nil {
+		return nil, badRequest("read body: %s", err.Error())
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return nil, badRequest("template is required")
+	}
+	if _, err := widget.ParseTemplate(string(body)); err != nil {
+		return nil, badRequest("invalid template: %s", err.Error())
+	}
+
+	return h.setWidgetFormat(request, name, string(body))
+}
+
+func (h *handler) v1DeleteWidgetFormat(request *http.Request) (interface{}, error) {
+	name := request.URL.Query().Get("name")
+	if _, ok := principalFrom(request.Context()).Widgets[name]; !ok {
+		return nil, &apiError{status: http.StatusNotFound, code: "not_found", message: "unknown widget format " + name}
+	}
+
+	return h.setWidgetFormat(request, name, "")
+}
+
+func (h *handler) setWidgetFormat(request *http.Request, name, tmpl string) (interface{}, error) {
+	principal := principalFrom(request.Context())
+
+	err := h.auth.SetWidget(request.Context(), principal.Name, name, tmpl)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil, badRequest("widget formats can be stored only for tokens issued by the bot")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	formats := map[string]string{}
+	for k, v := range principal.Widgets {
+		formats[k] = v
+	}
+	if tmpl == "" {
+		delete(formats, name)
+	} else {
+		formats[name] = tmpl
+	}
+
+	return widgetFormats{Formats: formats}, nil
+}
@@ -2,6 +2,7 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -9,13 +10,54 @@ import (
 	"time"
 
 	"github.com/unkeep/alfabooker/api"
+	"github.com/unkeep/alfabooker/auth"
 	"github.com/unkeep/alfabooker/budget"
 	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/i18n"
+	"github.com/unkeep/alfabooker/mailin"
 	"github.com/unkeep/alfabooker/tg"
+	"github.com/unkeep/alfabooker/web"
+	"github.com/unkeep/alfabooker/webhook"
 )
 
+// expiryCheckInterval is how often the budget is checked for expiration
+const expiryCheckInterval = time.Minute
+
+// errorReportTimeout limits reporting a controller error to the admin chat
+const errorReportTimeout = time.Second * 5
+
+// App is a running bot, its Handler serves the API, the dashboard, metrics and Telegram webhook updates
+type App struct {
+	Handler http.Handler
+
+	repo         *db.Repo
+	tgBot        *tg.Bot
+	polling      bool
+	stopPolling  context.CancelFunc
+	stopLoop     chan struct{}
+	loopDone     chan struct{}
+	cancel       context.CancelFunc
+	closeStreams context.CancelFunc
+}
+
+// NewHandler starts the app for a serverless runtime, which never shuts it down
 func NewHandler() (http.Handler, error) {
-	ctx := context.Background()
+	a, err := New()
+	if err != nil {
+		return nil, err
+	}
+
+	return a.Handler, nil
+}
+
+// New starts the app, it runs background jobs and receives Telegram updates until Shutdown
+func New() (_ *App, err error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer func() {
+		if err != nil {
+			cancel()
+		}
+	}()
 
 	log.Println("Run")
 	cfg, err := getConfig()
@@ -24,73 +66,267 @@ func NewHandler() (http.Handler, error) {
 	}
 
 	log.Println("GetRepo")
-	repo, err := db.GetRepo(ctx, cfg.MongoURI)
+	// the connection outlives background jobs, it is closed by Shutdown
+	repo, err := db.GetRepo(context.Background(), cfg.MongoURI)
 	if err != nil {
 		return nil, fmt.Errorf("db.GetRepo: %w", err)
 	}
 
 	log.Println("GetBudgetDomain")
-	budgetDomain := budget.NewDomain(repo.Budget)
+	budgetDomain := budget.NewDomain(repo)
+
+	authService := auth.NewService(repo.Tokens, cfg.APIAuthToken)
+	sessions := auth.NewSessions(repo.Sessions)
 
 	log.Println("GetBot")
 	msgChan := make(chan tg.UserMsg, 0)
-	tgBot, err := tg.GetBot(cfg.TgToken, func(msg tg.UserMsg) {
+	btnChan := make(chan tg.BtnClick, 0)
+	botCfg := tg.BotConfig{
+		Token:         cfg.TgToken,
+		APIEndpoint:   cfg.TgAPIEndpoint,
+		WebhookSecret: cfg.TgWebhookSecret,
+		UpdatesLog:    repo.Updates,
+	}
+	tgBot, err := tg.GetBot(botCfg, func(msg tg.UserMsg) {
 		msgChan <- msg
+	}, func(click tg.BtnClick) {
+		btnChan <- click
 	})
 	if err != nil {
 		return nil, fmt.Errorf("tg.GetBot: %w", err)
 	}
 
+	ob := newOutbox(repo.Outbox, tgBot)
+	go ob.run(ctx)
+
+	webhooks := webhook.NewDispatcher(repo.Webhooks, repo.Deliveries)
+	go webhooks.Run(ctx)
+
+	var bridge *mqttBridge
+	if cfg.MQTTURL != "" {
+		bridge, err = newMQTTBridge(cfg.MQTTURL, cfg.MQTTTopic, cfg.MQTTClientID, budgetDomain)
+		if err != nil {
+			return nil, fmt.Errorf("newMQTTBridge: %w", err)
+		}
+		go bridge.run(ctx)
+	}
+
 	c := controller{
 		cfg:          cfg,
 		repo:         repo,
 		tgBot:        tgBot,
 		budgetDomain: budgetDomain,
+		auth:         authService,
+		sessions:     sessions,
+		webhooks:     webhooks,
+		outbox:       ob,
+	}
+
+	// reportErr tells the admin about the error with its own timeout, since the error may be the expired one
+	reportErr := func(name string, param interface{}, err error) {
+		ctx, cancel := context.WithTimeout(context.Background(), errorReportTimeout)
+		defer cancel()
+
+		// plain text, since the error may contain Markdown which Telegram would reject
+		text := c.printer(ctx, cfg.TgAdminChatID).T(i18n.ControllerErr, name, err.Error(), param)
+		if err := c.notify(ctx, cfg.TgAdminChatID, text); err != nil {
+			log.Println("controller.notify:", err)
+		}
 	}
 
 	cc := func(name string, param interface{}, f func(ctx context.Context) error) {
 		ctx, cancel := context.WithTimeout(ctx, time.Second*10)
 		defer cancel()
 		if err := f(ctx); err != nil {
+			errorsTotal.Inc(name)
 			log.Printf("%s(%+v): %s\n", name, param, err.Error())
-			_, _ = tgBot.SendMessage(tg.BotMessage{
-				ChatID: cfg.TgAdminChatID,
-				Text: fmt.Sprintf("⚠️ controller: %s, error:\n```%s```\ncontext:\n```%+v```\n",
-					name, err.Error(), param),
-				TextMarkdown: true,
+			reportErr(name, param, err)
+		}
+	}
+
+	budgetDomain.Subscribe(func(_ context.Context, e budget.Event) {
+		cc("publishWebhooks", e.Type, func(ctx context.Context) error {
+			return webhooks.Publish(ctx, e)
+		})
+		if bridge != nil {
+			bridge.notify()
+		}
+
+		if e.Type == budget.EventStatementReconciled {
+			cc("reportReconciliation", e.Type, func(ctx context.Context) error {
+				return c.reportReconciliation(ctx, *e.Reconciliation)
 			})
+			return
+		}
+
+		// the ledger and alerts do not change the status message
+		if e.Type == budget.EventTransactionAdded || e.Type == budget.EventAlertRaised || e.Type == budget.EventBudgetExpired {
+			return
+		}
+
+		cc("refreshStatusMessages", e, func(ctx context.Context) error {
+			return c.refreshStatusMessages(ctx)
+		})
+	})
+
+	if cfg.SMTPAddr != "" {
+		mailServer := &mailin.Server{
+			Hostname:  cfg.SMTPHostname,
+			Allowed:   mailin.Allowlist(cfg.SMTPAllowedSenders),
+			Recipient: cfg.SMTPRecipient,
+			Handler: func(ctx context.Context, m mailin.Mail) error {
+				var err error
+				cc("handleEmailAlert", m.Subject, func(ctx context.Context) error {
+					_, err = budgetDomain.ApplySMS(ctx, budget.SMS{Text: m.Text, Sender: m.From, ReceivedAt: m.Date.Unix()})
+					// banks also send marketing emails, they are only logged by the receiver
+					if errors.Is(err, budget.ErrUnrecognizedSMS) {
+						return nil
+					}
+					return err
+				})
+				return err
+			},
 		}
+		go func() {
+			log.Println("serving SMTP on", cfg.SMTPAddr)
+			if err := mailServer.ListenAndServe(ctx, cfg.SMTPAddr); err != nil {
+				log.Println("mailServer.ListenAndServe:", err)
+			}
+		}()
 	}
 
-	log.Println("selecting channels")
 	go func() {
+		ticker := time.NewTicker(expiryCheckInterval)
+		defer ticker.Stop()
+
 		for {
 			select {
 			case <-ctx.Done():
 				return
+			case <-ticker.C:
+				cc("checkBudgetExpiry", nil, func(ctx context.Context) error {
+					return budgetDomain.CheckExpiry(ctx)
+				})
+			}
+		}
+	}()
+
+	log.Println("selecting channels")
+	stopLoop := make(chan struct{})
+	loopDone := make(chan struct{})
+	loop := &loopMonitor{}
+	go func() {
+		defer close(loopDone)
+		defer loop.stop()
+		for {
+			select {
+			case <-stopLoop:
+				return
 			case msg := <-msgChan:
+				loop.begin()
+				commandsTotal.Inc(commandLabel(msg))
 				cc("handleUserMessage", msg, func(ctx context.Context) error {
 					return c.handleUserMessage(ctx, msg)
 				})
+				loop.end()
+			case click := <-btnChan:
+				loop.begin()
+				commandsTotal.Inc("button")
+				cc("handleBtnClick", click, func(ctx context.Context) error {
+					return c.handleBtnClick(ctx, click)
+				})
+				loop.end()
 			}
 		}
 	}()
 
-	apiHandler := api.NewHandler(budgetDomain, cfg.APIAuthToken)
+	tgUpdatesPath := "/tgupdate"
+	pollingCtx, stopPolling := context.WithCancel(ctx)
+
+	switch cfg.TgUpdateMode {
+	case tg.UpdateModeWebhook:
+		webHookUrl := cfg.URL + tgUpdatesPath
+		if err := tgBot.SetWebhook(webHookUrl); err != nil {
+			log.Println("tgBot.SetWebhook error. But it's fine (should be already set)", err)
+		}
+	case tg.UpdateModePolling:
+		log.Println("StartPolling")
+		if err := tgBot.StartPolling(pollingCtx); err != nil {
+			stopPolling()
+			return nil, fmt.Errorf("tgBot.StartPolling: %w", err)
+		}
+	}
+
+	var smsVerifier *api.SMSVerifier
+	if cfg.SMSSigningSecret != "" {
+		smsVerifier = api.NewSMSVerifier(cfg.SMSSigningSecret, cfg.SMSSignatureSkew, repo.Nonces)
+	}
+	streamsCtx, closeStreams := context.WithCancel(ctx)
+	apiHandler := api.NewHandler(streamsCtx, budgetDomain, authService, smsVerifier)
+	webHandler := web.NewHandler(budgetDomain, sessions, func(ctx context.Context) i18n.Printer {
+		return c.printer(ctx, cfg.TgAdminChatID)
+	})
 
-	tgUpdatesPath := "/tgupdate/" + cfg.TgToken
+	collectBudgetMetrics(budgetDomain)
+	metricsPath := "/metrics"
+	serveMetrics := metricsHandler(cfg.MetricsToken)
 
-	webHookUrl := cfg.URL + tgUpdatesPath
-	if err := tgBot.SetWebhook(webHookUrl); err != nil {
-		log.Println("tgBot.SetWebhook error. But it's fine (should be already set)", err)
+	healthzPath, readyzPath := "/healthz", "/readyz"
+	checks := []readyCheck{
+		{name: "mongo", check: repo.Ping},
+		{name: "telegram", check: func(context.Context) error {
+			_, err := tgBot.API.GetMe()
+			return err
+		}},
+		{name: "message_loop", check: func(context.Context) error {
+			return loop.check(time.Now())
+		}},
+	}
+	if cfg.ReadySMSMaxAge > 0 {
+		checks = append(checks, readyCheck{name: "sms", check: func(ctx context.Context) error {
+			lastAt, err := budgetDomain.LastSMSAt(ctx)
+			if err != nil {
+				return err
+			}
+			return checkSMSAge(lastAt, cfg.ReadySMSMaxAge, time.Now())
+		}})
+	}
+	serveReadyz := readyzHandler(checks, readyCheckTimeout)
+
+	isWebPath := func(path string) bool {
+		return path == web.PathPrefix || strings.HasPrefix(path, web.PathPrefix+"/")
+	}
+
+	routeLabel := func(path string) string {
+		switch {
+		case strings.HasPrefix(path, api.PathPrefix):
+			return api.RouteLabel(path)
+		case isWebPath(path):
+			return web.RouteLabel(path)
+		case path == tgUpdatesPath || path == metricsPath || path == healthzPath || path == readyzPath:
+			return path
+		}
+		return "other"
 	}
 
-	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+	handler := instrument(routeLabel, http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 		switch {
 		case strings.HasPrefix(request.URL.Path, api.PathPrefix):
 			apiHandler.ServeHTTP(writer, request)
 			return
-		case request.URL.Path == tgUpdatesPath:
+		case isWebPath(request.URL.Path):
+			webHandler.ServeHTTP(writer, request)
+			return
+		case request.URL.Path == metricsPath:
+			serveMetrics.ServeHTTP(writer, request)
+			return
+		case request.URL.Path == healthzPath:
+			healthzHandler(writer, request)
+			return
+		case request.URL.Path == readyzPath:
+			serveReadyz(writer, request)
+			return
+		case cfg.TgUpdateMode == tg.UpdateModeWebhook && request.URL.Path == tgUpdatesPath:
 			tgBot.HandleUpdateRequest(writer, request)
 			return
 		default:
@@ -98,5 +334,57 @@ func NewHandler() (http.Handler, error) {
 			writer.WriteHeader(http.StatusNotFound)
 			return
 		}
-	}), nil
+	}))
+
+	return &App{
+		Handler:      handler,
+		repo:         repo,
+		tgBot:        tgBot,
+		polling:      cfg.TgUpdateMode == tg.UpdateModePolling,
+		stopPolling:  stopPolling,
+		stopLoop:     stopLoop,
+		loopDone:     loopDone,
+		cancel:       cancel,
+		closeStreams: closeStreams,
+	}, nil
+}
+
+// CloseStreams closes API event streams, which otherwise hold the HTTP server shutdown
+func (a *App) CloseStreams() {
+	a.closeStreams()
+}
+
+// Shutdown stops polling, waits for received updates to be handled, stops background jobs and disconnects
+// from Mongo. The HTTP server must be shut down before, so no webhook update is being received.
+func (a *App) Shutdown(ctx context.Context) error {
+	a.CloseStreams()
+	a.stopPolling()
+	if a.polling {
+		if err := wait(ctx, a.tgBot.PollingStopped()); err != nil {
+			return fmt.Errorf("wait polling: %w", err)
+		}
+	}
+
+	// updates are passed to the loop synchronously, so none is left once they are not received
+	close(a.stopLoop)
+	if err := wait(ctx, a.loopDone); err != nil {
+		return fmt.Errorf("wait message loop: %w", err)
+	}
+
+	a.cancel()
+
+	if err := a.repo.Close(ctx); err != nil {
+		return fmt.Errorf("repo.Close: %w", err)
+	}
+
+	return nil
+}
+
+func wait(ctx context.Context, done <-chan struct{}) error {
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
@@ -0,0 +1,65 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/unkeep/alfabooker/export"
+	"github.com/unkeep/alfabooker/i18n"
+	"github.com/unkeep/alfabooker/tg"
+)
+
+// sendExport handles "/export [format] [from] [to]" sending the ledger as a document
+func (c *controller) sendExport(ctx context.Context, chatID int64, args []string) error {
+	p := c.printer(ctx, chatID)
+
+	format := export.FormatCSV
+	if len(args) > 0 {
+		f, ok := export.ParseFormat(args[0])
+		if !ok || len(args) > 3 {
+			return c.sendText(chatID, p.T(i18n.ExportUsage))
+		}
+		format = f
+	}
+
+	var fromArg, toArg string
+	if len(args) > 1 {
+		fromArg = args[1]
+	}
+	if len(args) > 2 {
+		toArg = args[2]
+	}
+	from, to, err := export.ParseRange(fromArg, toArg)
+	if err != nil {
+		return c.sendText(chatID, p.T(i18n.ExportUsage))
+	}
+
+	txs, err := c.budgetDomain.GetTransactions(ctx, from, to)
+	if err != nil {
+		return fmt.Errorf("budgetDomain.GetTransactions: %w", err)
+	}
+
+	opts := export.RangeOptions(from, to, txs)
+
+	if len(txs) == 0 {
+		return c.sendText(chatID, p.T(i18n.ExportEmpty))
+	}
+
+	var buf bytes.Buffer
+	if err := export.Write(&buf, format, txs, opts); err != nil {
+		return fmt.Errorf("export.Write: %w", err)
+	}
+
+	doc := tg.BotDocument{
+		ChatID:  chatID,
+		Name:    export.FileName(format, opts.From, opts.To),
+		Data:    buf.Bytes(),
+		Caption: p.T(i18n.ExportCaption, len(txs), p.Date(opts.From), p.Date(opts.To)),
+	}
+	if _, err := c.tgBot.SendDocument(doc); err != nil {
+		return fmt.Errorf("tgBot.SendDocument: %w", err)
+	}
+
+	return nil
+}
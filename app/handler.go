@@ -2,23 +2,47 @@ package app
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/unkeep/alfabooker/auth"
 	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/chart"
 	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/i18n"
 	"github.com/unkeep/alfabooker/tg"
+	"github.com/unkeep/alfabooker/webhook"
 )
 
+const btnLangPrefix = "lang:"
+
 type controller struct {
 	repo  *db.Repo
 	tgBot *tg.Bot
 	cfg   config
 
 	budgetDomain *budget.Domain
+	auth         *auth.Service
+	sessions     *auth.Sessions
+	webhooks     *webhook.Dispatcher
+	// outbox delivers notifications which are not direct replies with retries
+	outbox *outbox
+}
+
+// editableCommands set values, so an edited one corrects the value set by the original message
+var editableCommands = []string{"card ", "cash ", "reserve "}
+
+func isEditable(text string) bool {
+	for _, cmd := range editableCommands {
+		if strings.HasPrefix(text, cmd) {
+			return true
+		}
+	}
+	return false
 }
 
 func (c *controller) handleUserMessage(ctx context.Context, msg tg.UserMsg) error {
@@ -28,9 +52,101 @@ func (c *controller) handleUserMessage(ctx context.Context, msg tg.UserMsg) erro
 		return fmt.Errorf("message from unknown chat: %+v", msg)
 	}
 
+	if msg.Document != nil {
+		if err := c.importStatement(ctx, msg.ChatID, msg.Document); err != nil {
+			return fmt.Errorf("importStatement: %w", err)
+		}
+		return nil
+	}
+
 	text := strings.TrimSpace(msg.Text)
 	text = strings.ToLower(text)
 
+	// re-applying other edited commands would apply them twice
+	if msg.Edited && !isEditable(text) {
+		log.Println("ignored edited command", msg)
+		return nil
+	}
+
+	switch text {
+	case "/new":
+		if err := c.startWizard(ctx, msg.ChatID, flowNewBudget); err != nil {
+			return fmt.Errorf("startWizard: %w", err)
+		}
+		return nil
+	case "/reconcile":
+		if err := c.startWizard(ctx, msg.ChatID, flowReconcile); err != nil {
+			return fmt.Errorf("startWizard: %w", err)
+		}
+		return nil
+	}
+
+	conv, inConversation, err := c.getActiveConversation(ctx, msg.ChatID)
+	if err != nil {
+		return fmt.Errorf("getActiveConversation: %w", err)
+	}
+
+	if inConversation {
+		if text == "/cancel" {
+			return c.cancelWizard(ctx, conv)
+		}
+		if !strings.HasPrefix(text, "/") {
+			if err := c.handleWizardText(ctx, conv, text); err != nil {
+				return fmt.Errorf("handleWizardText: %w", err)
+			}
+			return nil
+		}
+	}
+
+	if text == "/lang" || strings.HasPrefix(text, "/lang ") {
+		if err := c.chooseLang(ctx, msg.ChatID, strings.TrimSpace(strings.TrimPrefix(text, "/lang"))); err != nil {
+			return fmt.Errorf("chooseLang: %w", err)
+		}
+		return nil
+	}
+
+	if text == "/status" {
+		if err := c.enableStatus(ctx, msg.ChatID); err != nil {
+			return fmt.Errorf("enableStatus: %w", err)
+		}
+		return nil
+	}
+
+	if text == "/status off" {
+		if err := c.disableStatus(ctx, msg.ChatID); err != nil {
+			return fmt.Errorf("disableStatus: %w", err)
+		}
+		return nil
+	}
+
+	if text == "/outbox" {
+		if err := c.showOutboxStatus(ctx, msg.ChatID); err != nil {
+			return fmt.Errorf("showOutboxStatus: %w", err)
+		}
+		return nil
+	}
+
+	if text == "/token" || strings.HasPrefix(text, "/token ") {
+		if err := c.handleTokenCommand(ctx, msg.ChatID, strings.Fields(strings.TrimPrefix(text, "/token"))); err != nil {
+			return fmt.Errorf("handleTokenCommand: %w", err)
+		}
+		return nil
+	}
+
+	if text == "/webhook" || strings.HasPrefix(text, "/webhook ") {
+		if err := c.handleWebhookCommand(ctx, msg.ChatID, commandArgs(msg.Text)); err != nil {
+			return fmt.Errorf("handleWebhookCommand: %w", err)
+		}
+		return nil
+	}
+
+	if text == "/login" {
+		if err := c.sendLoginLink(ctx, msg.ChatID); err != nil {
+			return fmt.Errorf("sendLoginLink: %w", err)
+		}
+		return nil
+	}
+
 	if text == "/help" {
 		if err := c.showHelp(ctx, msg.ChatID); err != nil {
 			return fmt.Errorf("showHelp: %w", err)
@@ -44,6 +160,29 @@ func (c *controller) handleUserMessage(ctx context.Context, msg tg.UserMsg) erro
 		return nil
 	}
 
+	if text == "/chart" || strings.HasPrefix(text, "/chart ") {
+		past := 0
+		if arg := strings.TrimSpace(strings.TrimPrefix(text, "/chart")); arg != "" {
+			val, err := strconv.Atoi(arg)
+			if err != nil {
+				return fmt.Errorf("parse periods count: %w", err)
+			}
+			past = val
+		}
+
+		if err := c.showBalanceChart(ctx, msg.ChatID, past); err != nil {
+			return fmt.Errorf("showBalanceChart: %w", err)
+		}
+		return nil
+	}
+
+	if text == "/export" || strings.HasPrefix(text, "/export ") {
+		if err := c.sendExport(ctx, msg.ChatID, strings.Fields(strings.TrimPrefix(text, "/export"))); err != nil {
+			return fmt.Errorf("sendExport: %w", err)
+		}
+		return nil
+	}
+
 	if strings.HasPrefix(text, "start ") {
 		text = strings.TrimPrefix(text, "start ")
 		val, err := strconv.Atoi(text)
@@ -51,8 +190,8 @@ func (c *controller) handleUserMessage(ctx context.Context, msg tg.UserMsg) erro
 			return fmt.Errorf("parse days: %w", err)
 		}
 
-		if err := c.updateBudgetTiming(ctx, val); err != nil {
-			return fmt.Errorf("updateBudgetTiming: %w", err)
+		if err := c.budgetDomain.StartBudget(ctx, val); err != nil {
+			return fmt.Errorf("budgetDomain.StartBudget: %w", err)
 		}
 		return nil
 	}
@@ -64,20 +203,23 @@ func (c *controller) handleUserMessage(ctx context.Context, msg tg.UserMsg) erro
 			return fmt.Errorf("parse cash value: %w", err)
 		}
 
-		if err := c.setCash(ctx, val); err != nil {
-			return fmt.Errorf("setCash: %w", err)
+		if err := c.budgetDomain.SetCash(ctx, float64(val)); err != nil {
+			return fmt.Errorf("budgetDomain.SetCash: %w", err)
 		}
 		return nil
 	}
 
 	if strings.HasPrefix(text, "add cash ") {
 		text = strings.TrimPrefix(text, "add cash ")
-		if val, err := strconv.Atoi(text); err != nil {
-			if err := c.addCash(ctx, val); err != nil {
-				return fmt.Errorf("addCash: %w", err)
-			}
-			return nil
+		val, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("parse cash value: %w", err)
 		}
+
+		if err := c.budgetDomain.AddCash(ctx, float64(val)); err != nil {
+			return fmt.Errorf("budgetDomain.AddCash: %w", err)
+		}
+		return nil
 	}
 
 	if strings.HasPrefix(text, "card ") {
@@ -122,131 +264,197 @@ func (c *controller) handleUserMessage(ctx context.Context, msg tg.UserMsg) erro
 		return nil
 	}
 
-	if strings.HasPrefix(text, "add budget") {
+	if strings.HasPrefix(text, "add budget ") {
 		text = strings.TrimPrefix(text, "add budget ")
-		if val, err := strconv.Atoi(text); err != nil {
-			if err := c.addBudget(ctx, val); err != nil {
-				return fmt.Errorf("addBudget: %w", err)
-			}
-			return nil
+		val, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("parse budget value: %w", err)
+		}
+
+		if err := c.budgetDomain.AddBudget(ctx, float64(val)); err != nil {
+			return fmt.Errorf("budgetDomain.AddBudget: %w", err)
+		}
+		return nil
+	}
+
+	// the forwarder may be down, so bank SMS are also pasted or forwarded to the chat
+	if !msg.Edited {
+		if err := c.handlePastedSMS(ctx, msg.ChatID, msg.Text); err != nil {
+			return fmt.Errorf("handlePastedSMS: %w", err)
 		}
 	}
 
 	return nil
 }
 
-func (c *controller) addCash(ctx context.Context, val int) error {
-	b, err := c.repo.Budget.Get(ctx)
-	if err != nil && err != db.ErrNotFound {
-		return fmt.Errorf("Budget.Get: %w", err)
+func (c *controller) sendText(chatID int64, text string) error {
+	msg := tg.BotMessage{
+		ChatID: chatID,
+		Text:   text,
+	}
+	if _, err := c.tgBot.SendMessage(msg); err != nil {
+		return fmt.Errorf("tgBot.SendMessage: %w", err)
 	}
-	b.CashBalance += float64(val)
 
-	return c.repo.Budget.Save(ctx, b)
+	return nil
 }
 
-func (c *controller) setCash(ctx context.Context, val int) error {
-	b, err := c.repo.Budget.Get(ctx)
-	if err != nil && err != db.ErrNotFound {
-		return fmt.Errorf("Budget.Get: %w", err)
+// notify queues the text to the chat, so it survives rate limits and Telegram outages
+func (c *controller) notify(ctx context.Context, chatID int64, text string) error {
+	if err := c.outbox.enqueue(ctx, tg.BotMessage{ChatID: chatID, Text: text}); err != nil {
+		return fmt.Errorf("outbox.enqueue: %w", err)
 	}
-	b.CashBalance = float64(val)
 
-	return c.repo.Budget.Save(ctx, b)
+	return nil
 }
 
-func (c *controller) addBudget(ctx context.Context, val int) error {
-	b, err := c.repo.Budget.Get(ctx)
-	if err != nil && err != db.ErrNotFound {
-		return fmt.Errorf("Budget.Get: %w", err)
+func (c *controller) showHelp(ctx context.Context, chatID int64) error {
+	return c.sendText(chatID, c.printer(ctx, chatID).T(i18n.Help))
+}
+
+func (c *controller) showBudgetStat(ctx context.Context, chatID int64) error {
+	stat, err := c.budgetDomain.GetStat(ctx)
+	if err != nil {
+		return fmt.Errorf("budgetDomain.GetStat: %w", err)
 	}
-	b.Amount += float64(val)
-	b.StartedAt = time.Now().Unix()
 
-	return c.repo.Budget.Save(ctx, b)
+	p := c.printer(ctx, chatID)
+	text := p.T(i18n.Stat,
+		p.Number(stat.AccountBalance),
+		p.Number(stat.CashBalance),
+		p.Number(stat.ReservedBalance),
+		p.Number(stat.TotalBalance),
+		p.SignedNumber(stat.BalanceDeviation),
+		p.Decimal(stat.BudgetDaysToExpiration, 1),
+		p.Number(stat.DailyAverageSpending),
+	)
+
+	return c.sendText(chatID, text)
 }
 
-func (c *controller) updateBudgetTiming(ctx context.Context, days int) error {
-	b, err := c.repo.Budget.Get(ctx)
-	if err != nil && err != db.ErrNotFound {
-		return fmt.Errorf("Budget.Get: %w", err)
+func (c *controller) showBalanceChart(ctx context.Context, chatID int64, pastCount int) error {
+	pr := c.printer(ctx, chatID)
+	periods, err := c.budgetDomain.GetPeriods(ctx, pastCount)
+	if errors.Is(err, db.ErrNotFound) {
+		return c.sendText(chatID, pr.T(i18n.ChartNoBudget))
+	}
+	if err != nil {
+		return fmt.Errorf("budgetDomain.GetPeriods: %w", err)
 	}
-	now := time.Now()
-	b.Amount = b.Balance + b.CashBalance - b.ReservedValue
-	b.StartedAt = now.Unix()
-	b.ExpiresAt = now.Add(time.Hour * time.Duration(24*days)).Unix()
 
-	return c.repo.Budget.Save(ctx, b)
-}
+	sent := 0
+	for _, p := range periods {
+		data, err := chart.RenderBalance(p)
+		// a budget which has not been started has nothing to show
+		if errors.Is(err, chart.ErrEmptyPeriod) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("chart.RenderBalance: %w", err)
+		}
 
-func (c *controller) showHelp(ctx context.Context, chatID int64) error {
-	msgText := `
-?           - show statistics
+		caption := pr.T(i18n.ChartCaption,
+			pr.Date(time.Unix(p.StartedAt, 0)),
+			pr.Date(time.Unix(p.ExpiresAt, 0)),
+			pr.Number(p.Amount),
+		)
+		if len(p.Points) > 0 {
+			last := p.Points[len(p.Points)-1]
+			caption += "\n" + pr.T(i18n.ChartBalance, pr.Number(last.Total), pr.Number(p.EstimatedBalance(last.At)))
+		}
 
-start <num>   - start new budget tracking for <num> days 
+		photo := tg.BotPhoto{
+			ChatID:  chatID,
+			Name:    "chart.png",
+			Data:    data,
+			Caption: caption,
+		}
+		if _, err := c.tgBot.SendPhoto(photo); err != nil {
+			return fmt.Errorf("tgBot.SendPhoto: %w", err)
+		}
+		sent++
+	}
 
-card          - set amount on card to <num> 
+	if sent == 0 {
+		return c.sendText(chatID, pr.T(i18n.ChartNoBudget))
+	}
 
-cash <num>    - set amount of cash to <num> 
+	return nil
+}
 
-add cash <num> - add/decrease cache by <num>
+// printer returns a message printer of the chat language
+func (c *controller) printer(ctx context.Context, chatID int64) i18n.Printer {
+	chat, err := c.repo.Chats.Get(ctx, chatID)
+	if err != nil && err != db.ErrNotFound {
+		log.Println("Chats.Get:", err)
+	}
 
-align <num>   - decrease budget amount by <num> and it's duration proportionately'
+	lang := i18n.Lang(chat.Lang)
+	if lang == "" {
+		lang = i18n.Lang(c.cfg.DefaultLang)
+	}
 
-reserve <num> - set reserved balance value (will not be counted in total balance)
+	return i18n.NewPrinter(lang)
+}
 
-add budget   - add/decrease budget by <num>
-`
+func (c *controller) chooseLang(ctx context.Context, chatID int64, code string) error {
+	if code == "" {
+		var btns []tg.Btn
+		for _, lang := range i18n.Langs {
+			btns = append(btns, tg.Btn{ID: btnLangPrefix + string(lang), Text: lang.Name()})
+		}
 
-	msgText = strings.TrimPrefix(msgText, "\n")
+		msg := tg.BotMessage{
+			ChatID: chatID,
+			Text:   c.printer(ctx, chatID).T(i18n.LangChoose),
+			Btns:   btns,
+		}
+		if _, err := c.tgBot.SendMessage(msg); err != nil {
+			return fmt.Errorf("tgBot.SendMessage: %w", err)
+		}
+		return nil
+	}
 
-	msg := tg.BotMessage{
-		ChatID: chatID,
-		Text:   msgText,
+	lang, ok := i18n.ParseLang(code)
+	if !ok {
+		return fmt.Errorf("unsupported language: %s", code)
 	}
-	if _, err := c.tgBot.SendMessage(msg); err != nil {
-		return fmt.Errorf("tgBot.SendMessage: %w", err)
+
+	chat, err := c.repo.Chats.Get(ctx, chatID)
+	if err != nil && err != db.ErrNotFound {
+		return fmt.Errorf("Chats.Get: %w", err)
 	}
+	chat.ID = chatID
+	chat.Lang = string(lang)
 
-	return nil
+	if err := c.repo.Chats.Save(ctx, chat); err != nil {
+		return fmt.Errorf("Chats.Save: %w", err)
+	}
 
+	return c.sendText(chatID, i18n.NewPrinter(lang).T(i18n.LangSet))
 }
 
-func (c *controller) showBudgetStat(ctx context.Context, chatID int64) error {
-	stat, err := c.budgetDomain.GetStat(ctx)
-	if err != nil {
-		return fmt.Errorf("budgetDomain.GetStat: %w", err)
+func (c *controller) handleBtnClick(ctx context.Context, click tg.BtnClick) error {
+	if click.ChatID != c.cfg.TgAdminChatID {
+		return fmt.Errorf("btn click from unknown chat: %+v", click)
 	}
 
-	balanceDeviationStr := fmt.Sprint(int(stat.BalanceDeviation))
-	if int(stat.BalanceDeviation) > 0 {
-		balanceDeviationStr = "+" + balanceDeviationStr
-	}
-
-	text := fmt.Sprintf(`
-card: %d, cash: %d, reserved: %d
-total: %d
-%s from estimated balance
-%.1f days left
-%d avg daily spending`,
-		int(stat.AccountBalance),
-		int(stat.CashBalance),
-		int(stat.ReservedBalance),
-		int(stat.TotalBalance),
-		balanceDeviationStr,
-		stat.BudgetDaysToExpiration,
-		int(stat.DailyAverageSpending),
-	)
-	text = strings.TrimPrefix(text, "\n")
-
-	msg := tg.BotMessage{
-		ChatID: chatID,
-		Text:   text,
+	if strings.HasPrefix(click.BtnID, btnLangPrefix) {
+		if err := c.tgBot.EditBtns(click.ChatID, click.MessageID, nil); err != nil {
+			return fmt.Errorf("tgBot.EditBtns: %w", err)
+		}
+		return c.chooseLang(ctx, click.ChatID, strings.TrimPrefix(click.BtnID, btnLangPrefix))
 	}
 
-	if _, err := c.tgBot.SendMessage(msg); err != nil {
-		return fmt.Errorf("tgBot.SendMessage: %w", err)
-	}
+	return c.handleWizardBtnClick(ctx, click)
+}
 
-	return nil
+// commandArgs returns arguments of the command in their original case, only the subcommand is lowercased,
+// since e.g. webhook URLs are case-sensitive
+func commandArgs(text string) []string {
+	args := strings.Fields(text)[1:]
+	if len(args) > 0 {
+		args[0] = strings.ToLower(args[0])
+	}
+	return args
 }
@@ -0,0 +1,224 @@
+package app
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/tg"
+	"github.com/unkeep/alfabooker/tg/tgtest"
+)
+
+const testChatID = 42
+
+// newTestController creates a controller talking to a fake Telegram server,
+// AB_TEST_MONGOURI must point to a disposable database
+func newTestController(t *testing.T) (*controller, *tgtest.Server) {
+	mongoURI := os.Getenv("AB_TEST_MONGOURI")
+	if mongoURI == "" {
+		t.Skip("AB_TEST_MONGOURI is not set")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	repo, err := db.GetRepo(ctx, mongoURI)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := tgtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	tgBot, err := tg.GetBot(tg.BotConfig{Token: tgtest.Token, APIEndpoint: srv.Endpoint()},
+		func(tg.UserMsg) {}, func(tg.BtnClick) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := &controller{
+		cfg:          config{TgAdminChatID: testChatID, DefaultLang: "en"},
+		repo:         repo,
+		tgBot:        tgBot,
+		budgetDomain: budget.NewDomain(repo),
+		outbox:       newOutbox(repo.Outbox, tgBot),
+	}
+
+	if err := c.repo.Chats.Save(ctx, db.Chat{ID: testChatID, Lang: "en"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.repo.Conversations.Delete(ctx, testChatID); err != nil {
+		t.Fatal(err)
+	}
+
+	return c, srv
+}
+
+func send(t *testing.T, c *controller, text string) {
+	t.Helper()
+
+	if err := c.handleUserMessage(context.Background(), tg.UserMsg{ChatID: testChatID, Text: text}); err != nil {
+		t.Fatalf("%s: %s", text, err)
+	}
+}
+
+func lastText(t *testing.T, srv *tgtest.Server) string {
+	t.Helper()
+
+	texts := srv.SentTexts(testChatID)
+	if len(texts) == 0 {
+		t.Fatal("no messages sent")
+	}
+	return texts[len(texts)-1]
+}
+
+func TestHandleUserMessage(t *testing.T) {
+	t.Run("help", func(t *testing.T) {
+		c, srv := newTestController(t)
+
+		send(t, c, "/help")
+
+		if !strings.Contains(lastText(t, srv), "show statistics") {
+			t.Error(lastText(t, srv))
+		}
+	})
+
+	t.Run("balance commands", func(t *testing.T) {
+		c, srv := newTestController(t)
+
+		send(t, c, "start 10")
+		send(t, c, "card 1000")
+		send(t, c, "cash 300")
+		send(t, c, "reserve 100")
+		send(t, c, "?")
+
+		text := lastText(t, srv)
+		if !strings.HasPrefix(text, "card: 1,000, cash: 300, reserved: 100\ntotal: 1,200") {
+			t.Error(text)
+		}
+	})
+
+	t.Run("reconcile", func(t *testing.T) {
+		c, srv := newTestController(t)
+
+		send(t, c, "start 10")
+		send(t, c, "/reconcile")
+		send(t, c, "250")
+		send(t, c, "1500")
+
+		text := lastText(t, srv)
+		if !strings.HasPrefix(text, "card: 1,500, cash: 250") {
+			t.Error(text)
+		}
+	})
+
+	t.Run("status message", func(t *testing.T) {
+		c, srv := newTestController(t)
+		c.budgetDomain.Subscribe(func(ctx context.Context, _ budget.Event) {
+			if err := c.refreshStatusMessages(ctx); err != nil {
+				t.Error(err)
+			}
+		})
+
+		send(t, c, "cash 0")
+		send(t, c, "reserve 0")
+		send(t, c, "start 10")
+		send(t, c, "/status")
+		if pins := srv.Requests("pinChatMessage"); len(pins) != 1 {
+			t.Fatalf("unexpected pins %+v", pins)
+		}
+
+		send(t, c, "card 700")
+		edits := srv.Requests("editMessageText")
+		if len(edits) != 1 || !strings.Contains(edits[0].Params["text"], "balance: 700") {
+			t.Errorf("unexpected edits %+v", edits)
+		}
+
+		send(t, c, "/status off")
+	})
+
+	t.Run("unknown chat", func(t *testing.T) {
+		c, srv := newTestController(t)
+
+		err := c.handleUserMessage(context.Background(), tg.UserMsg{ChatID: testChatID + 1, Text: "/help"})
+		if err == nil {
+			t.Error("error expected")
+		}
+		if texts := srv.SentTexts(testChatID + 1); len(texts) != 0 {
+			t.Errorf("unexpected messages %+v", texts)
+		}
+	})
+}
+
+func TestPolledUpdatesWithoutMongo(t *testing.T) {
+	srv := tgtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	// the controller has no repo, the updates below must be handled before it is needed
+	c := &controller{cfg: config{TgAdminChatID: testChatID}}
+	handled := make(chan error, 2)
+	tgBot, err := tg.GetBot(tg.BotConfig{Token: tgtest.Token, APIEndpoint: srv.Endpoint()},
+		func(msg tg.UserMsg) { handled <- c.handleUserMessage(context.Background(), msg) }, func(tg.BtnClick) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.tgBot = tgBot
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := tgBot.StartPolling(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	srv.InjectUpdate(srv.NewMessageUpdate(testChatID+1, "/new"))
+	edited := srv.NewMessageUpdate(testChatID, "/new")
+	edited.EditedMessage, edited.Message = edited.Message, nil
+	srv.InjectUpdate(edited)
+
+	for i, want := range []string{"unknown chat", ""} {
+		select {
+		case err := <-handled:
+			if want == "" && err != nil || want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+				t.Errorf("update %d: unexpected error %v", i, err)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatalf("update %d is not handled", i)
+		}
+	}
+
+	if reqs := srv.Requests("sendMessage"); len(reqs) != 0 {
+		t.Errorf("unexpected replies %+v", reqs)
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	args := commandArgs("/webhook ADD https://ha.local/api/webhook/AbC transaction_added")
+	if len(args) != 3 || args[0] != "add" || args[1] != "https://ha.local/api/webhook/AbC" {
+		t.Errorf("unexpected args %q", args)
+	}
+
+	if args := commandArgs("/webhook"); len(args) != 0 {
+		t.Errorf("unexpected args %q", args)
+	}
+}
+
+func TestEditedCommands(t *testing.T) {
+	// the controller has no dependencies, so a command which is not ignored panics
+	c := &controller{cfg: config{TgAdminChatID: testChatID}}
+
+	for _, text := range []string{"align 100", "start 10", "add cash 5", "add budget 50", "/new", "/token new ci admin"} {
+		if err := c.handleUserMessage(context.Background(), tg.UserMsg{ChatID: testChatID, Text: text, Edited: true}); err != nil {
+			t.Errorf("%s: %s", text, err)
+		}
+	}
+
+	for _, text := range []string{"card 100", "Cash 5", "reserve 10"} {
+		if !isEditable(strings.ToLower(text)) {
+			t.Errorf("%s is not editable", text)
+		}
+	}
+}
@@ -1,8 +1,156 @@
 package app
 
-import "net/http"
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
 
-func healthcheckHandler(w http.ResponseWriter, _ *http.Request) {
-	w.WriteHeader(http.StatusOK)
-	w.Write([]byte("OK"))
+// readyCheckTimeout limits a single readiness check
+const readyCheckTimeout = time.Second * 5
+
+// maxHandlingTime is how long a single update may be handled before the message loop is considered stuck,
+// handlers are limited to 10 seconds, so it is never reached by a healthy loop
+const maxHandlingTime = time.Minute
+
+// Health statuses
+const (
+	healthOK   = "ok"
+	healthFail = "fail"
+)
+
+// readyCheck is a dependency check of the readiness probe
+type readyCheck struct {
+	name  string
+	check func(ctx context.Context) error
+}
+
+type checkResult struct {
+	Status    string `json:"status"`
+	LatencyMS int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
+
+type healthReport struct {
+	Status string                 `json:"status"`
+	Checks map[string]checkResult `json:"checks,omitempty"`
+}
+
+// healthzHandler reports that the process is alive
+func healthzHandler(w http.ResponseWriter, _ *http.Request) {
+	writeHealth(w, healthReport{Status: healthOK})
+}
+
+// readyzHandler runs the checks concurrently and responds with 503 if any of them fails
+func readyzHandler(checks []readyCheck, timeout time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := healthReport{Status: healthOK, Checks: make(map[string]checkResult, len(checks))}
+
+		var mu sync.Mutex
+		var wg sync.WaitGroup
+		for _, c := range checks {
+			wg.Add(1)
+			go func(c readyCheck) {
+				defer wg.Done()
+				res := runCheck(r.Context(), c, timeout)
+
+				mu.Lock()
+				defer mu.Unlock()
+				report.Checks[c.name] = res
+				if res.Status != healthOK {
+					report.Status = healthFail
+				}
+			}(c)
+		}
+		wg.Wait()
+
+		writeHealth(w, report)
+	}
+}
+
+// runCheck runs the check within the timeout, also if the check itself ignores the context
+func runCheck(ctx context.Context, c readyCheck, timeout time.Duration) checkResult {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	started := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		err = fmt.Errorf("timeout: %w", ctx.Err())
+	}
+
+	res := checkResult{Status: healthOK, LatencyMS: time.Since(started).Milliseconds()}
+	if err != nil {
+		res.Status = healthFail
+		res.Error = err.Error()
+	}
+
+	return res
+}
+
+func writeHealth(w http.ResponseWriter, report healthReport) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if report.Status != healthOK {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	_ = json.NewEncoder(w).Encode(report)
+}
+
+// loopMonitor tracks the message loop for the readiness probe
+type loopMonitor struct {
+	// busySince is unix time in nanoseconds the current update is handled since, 0 if the loop is idle
+	busySince atomic.Int64
+	stopped   atomic.Bool
+}
+
+func (m *loopMonitor) begin() {
+	m.busySince.Store(time.Now().UnixNano())
+}
+
+func (m *loopMonitor) end() {
+	m.busySince.Store(0)
+}
+
+func (m *loopMonitor) stop() {
+	m.stopped.Store(true)
+}
+
+// check reports whether the loop is running and not stuck on an update
+func (m *loopMonitor) check(now time.Time) error {
+	if m.stopped.Load() {
+		return fmt.Errorf("message loop is stopped")
+	}
+
+	if since := m.busySince.Load(); since != 0 {
+		if busy := now.Sub(time.Unix(0, since)); busy > maxHandlingTime {
+			return fmt.Errorf("an update is handled for %s", busy.Round(time.Second))
+		}
+	}
+
+	return nil
+}
+
+// checkSMSAge fails if the latest SMS has been received longer than maxAge ago
+func checkSMSAge(lastAt int64, maxAge time.Duration, now time.Time) error {
+	if lastAt == 0 {
+		return fmt.Errorf("no SMS has been received")
+	}
+
+	if age := now.Sub(time.Unix(lastAt, 0)); age > maxAge {
+		return fmt.Errorf("the last SMS has been received %s ago", age.Round(time.Minute))
+	}
+
+	return nil
 }
@@ -0,0 +1,89 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestReadyz(t *testing.T) {
+	ok := func(context.Context) error { return nil }
+	failing := func(context.Context) error { return errors.New("unreachable") }
+	hanging := func(context.Context) error { select {} }
+
+	tests := []struct {
+		name   string
+		checks []readyCheck
+		status int
+		failed []string
+	}{
+		{"ready", []readyCheck{{"a", ok}, {"b", ok}}, http.StatusOK, nil},
+		{"failing", []readyCheck{{"a", ok}, {"b", failing}}, http.StatusServiceUnavailable, []string{"b"}},
+		{"hanging", []readyCheck{{"a", hanging}, {"b", ok}}, http.StatusServiceUnavailable, []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			readyzHandler(tt.checks, time.Millisecond*50)(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			if w.Code != tt.status {
+				t.Errorf("unexpected status %d", w.Code)
+			}
+
+			var report healthReport
+			if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+				t.Fatal(err)
+			}
+			if len(report.Checks) != len(tt.checks) {
+				t.Errorf("unexpected checks %+v", report.Checks)
+			}
+			for _, name := range tt.failed {
+				if res := report.Checks[name]; res.Status != healthFail || res.Error == "" {
+					t.Errorf("%s is not failed: %+v", name, res)
+				}
+			}
+		})
+	}
+}
+
+func TestLoopMonitor(t *testing.T) {
+	var m loopMonitor
+	now := time.Now()
+
+	m.begin()
+	if err := m.check(now); err != nil {
+		t.Error(err)
+	}
+	if err := m.check(now.Add(maxHandlingTime * 2)); err == nil {
+		t.Error("stuck loop is not detected")
+	}
+
+	m.end()
+	if err := m.check(now.Add(maxHandlingTime * 2)); err != nil {
+		t.Error(err)
+	}
+
+	m.stop()
+	if err := m.check(now); err == nil {
+		t.Error("stopped loop is not detected")
+	}
+}
+
+func TestCheckSMSAge(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	if err := checkSMSAge(now.Add(-time.Hour).Unix(), time.Hour*24, now); err != nil {
+		t.Error(err)
+	}
+	if err := checkSMSAge(now.Add(-time.Hour*25).Unix(), time.Hour*24, now); err == nil {
+		t.Error("old SMS is not detected")
+	}
+	if err := checkSMSAge(0, time.Hour*24, now); err == nil {
+		t.Error("missing SMS is not detected")
+	}
+}
@@ -0,0 +1,34 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/unkeep/alfabooker/i18n"
+	"github.com/unkeep/alfabooker/tg"
+	"github.com/unkeep/alfabooker/web"
+)
+
+// sendLoginLink sends a one time link to the web dashboard
+func (c *controller) sendLoginLink(ctx context.Context, chatID int64) error {
+	p := c.printer(ctx, chatID)
+
+	if c.cfg.URL == "" {
+		return c.sendText(chatID, p.T(i18n.LoginNoURL))
+	}
+
+	secret, err := c.sessions.IssueLoginLink(ctx)
+	if err != nil {
+		return fmt.Errorf("sessions.IssueLoginLink: %w", err)
+	}
+
+	link := strings.TrimSuffix(c.cfg.URL, "/") + web.LoginPath + "?t=" + url.QueryEscape(secret)
+	msg := tg.BotMessage{ChatID: chatID, Text: p.T(i18n.LoginLink, link), NoPreview: true}
+	if _, err := c.tgBot.SendMessage(msg); err != nil {
+		return fmt.Errorf("tgBot.SendMessage: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package app
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/metrics"
+	"github.com/unkeep/alfabooker/tg"
+)
+
+var (
+	totalBalanceGauge     = metrics.NewGauge("alfabooker_total_balance", "Card and cash balance without the reserve.")
+	balanceDeviationGauge = metrics.NewGauge("alfabooker_balance_deviation", "Difference of the total balance and the estimated one.")
+	daysLeftGauge         = metrics.NewGauge("alfabooker_budget_days_left", "Days left till the budget expiration.")
+	dailyAverageGauge     = metrics.NewGauge("alfabooker_daily_average_spending", "Average daily spending of the current budget.")
+	todayAllowanceGauge   = metrics.NewGauge("alfabooker_today_allowance", "What can be spent till the end of the day staying on the estimated balance.")
+
+	commandsTotal = metrics.NewCounter("alfabooker_telegram_commands_total", "Telegram messages and button clicks handled by the command.", "command")
+	errorsTotal   = metrics.NewCounter("alfabooker_controller_errors_total", "Errors returned by controllers.", "controller")
+
+	requestDuration = metrics.NewHistogram("alfabooker_http_request_duration_seconds", "HTTP request latency by route.", metrics.DefaultBuckets, "route", "method", "code")
+)
+
+// commands are message prefixes counted as separate commands, longer prefixes go first
+var commands = []string{
+	"/new", "/reconcile", "/cancel", "/lang", "/status", "/outbox", "/help", "/chart", "/export", "/token", "/login", "/webhook",
+	"?", "start", "add cash", "cash", "card", "align", "reserve", "add budget",
+}
+
+// commandLabel returns a command of the message to be used as a metric label
+func commandLabel(msg tg.UserMsg) string {
+	if msg.Document != nil {
+		return "document"
+	}
+
+	text := strings.ToLower(strings.TrimSpace(msg.Text))
+	for _, cmd := range commands {
+		if text == cmd || strings.HasPrefix(text, cmd+" ") {
+			return cmd
+		}
+	}
+
+	return "other"
+}
+
+// collectBudgetMetrics sets budget gauges on every scrape
+func collectBudgetMetrics(budgetDomain *budget.Domain) {
+	metrics.Default.OnCollect(func(ctx context.Context) {
+		stat, err := budgetDomain.GetStat(ctx)
+		if err != nil {
+			log.Println("budgetDomain.GetStat:", err)
+			return
+		}
+
+		totalBalanceGauge.Set(stat.TotalBalance)
+		balanceDeviationGauge.Set(stat.BalanceDeviation)
+		daysLeftGauge.Set(stat.BudgetDaysToExpiration)
+		dailyAverageGauge.Set(stat.DailyAverageSpending)
+		todayAllowanceGauge.Set(stat.TodayAllowance)
+	})
+}
+
+// metricsHandler serves metrics, requiring the bearer token if it is set
+func metricsHandler(token string) http.Handler {
+	h := metrics.Default.Handler()
+
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if token != "" {
+			got := strings.TrimPrefix(request.Header.Get("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writer.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+		}
+
+		h.ServeHTTP(writer, request)
+	})
+}
+
+// statusRecorder remembers the response status
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// Flush keeps streaming responses working
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// instrument observes latencies of requests served by h
+func instrument(route func(path string) string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		started := time.Now()
+		rec := &statusRecorder{ResponseWriter: writer, status: http.StatusOK}
+
+		h.ServeHTTP(rec, request)
+
+		requestDuration.Observe(time.Since(started).Seconds(), route(request.URL.Path), request.Method, strconv.Itoa(rec.status))
+	})
+}
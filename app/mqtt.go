@@ -0,0 +1,192 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/mqtt"
+)
+
+const (
+	// mqttRepublishInterval refreshes time dependent values like days left
+	mqttRepublishInterval = time.Minute * 5
+	// mqttMaxReconnectDelay caps the reconnect backoff
+	mqttMaxReconnectDelay = time.Minute * 5
+)
+
+// mqttBridge publishes retained budget status topics and applies commands received on <prefix>/cmd/...
+type mqttBridge struct {
+	opts         mqtt.Options
+	prefix       string
+	budgetDomain *budget.Domain
+
+	wake chan struct{}
+}
+
+func newMQTTBridge(brokerURL, prefix, clientID string, budgetDomain *budget.Domain) (*mqttBridge, error) {
+	opts, err := mqtt.ParseURL(brokerURL)
+	if err != nil {
+		return nil, fmt.Errorf("mqtt.ParseURL: %w", err)
+	}
+	opts.ClientID = clientID
+	opts.Will = &mqtt.Message{Topic: prefix + "/online", Payload: []byte("false"), Retain: true}
+
+	return &mqttBridge{
+		opts:         opts,
+		prefix:       strings.TrimSuffix(prefix, "/"),
+		budgetDomain: budgetDomain,
+		wake:         make(chan struct{}, 1),
+	}, nil
+}
+
+// notify schedules publishing of the current status
+func (b *mqttBridge) notify() {
+	select {
+	case b.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run keeps the connection to the broker until ctx is done
+func (b *mqttBridge) run(ctx context.Context) {
+	for attempt := 0; ctx.Err() == nil; attempt++ {
+		connected, err := b.session(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Println("mqttBridge.session:", err)
+		}
+		if connected {
+			attempt = 0
+		}
+
+		delay := time.Second << uint(attempt)
+		if delay > mqttMaxReconnectDelay || delay <= 0 {
+			delay = mqttMaxReconnectDelay
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(delay):
+		}
+	}
+}
+
+// session serves a single connection and reports whether it has been established
+func (b *mqttBridge) session(ctx context.Context) (bool, error) {
+	connectCtx, cancel := context.WithTimeout(ctx, time.Second*10)
+	client, err := mqtt.Connect(connectCtx, b.opts)
+	cancel()
+	if err != nil {
+		return false, fmt.Errorf("mqtt.Connect: %w", err)
+	}
+	defer client.Close()
+	log.Println("connected to MQTT broker", b.opts.Addr)
+
+	if err := client.Publish(mqtt.Message{Topic: b.prefix + "/online", Payload: []byte("true"), Retain: true}); err != nil {
+		return true, fmt.Errorf("Publish: %w", err)
+	}
+	if err := client.Subscribe(ctx, b.prefix+"/cmd/#"); err != nil {
+		return true, fmt.Errorf("Subscribe: %w", err)
+	}
+
+	ticker := time.NewTicker(mqttRepublishInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := b.publishStatus(ctx, client); err != nil {
+			log.Println("mqttBridge.publishStatus:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			// the broker publishes the will only if the connection is lost, not on a disconnect
+			if err := client.Publish(mqtt.Message{Topic: b.prefix + "/online", Payload: []byte("false"), Retain: true}); err != nil {
+				return true, fmt.Errorf("Publish: %w", err)
+			}
+			return true, nil
+		case <-client.Done():
+			return true, client.Err()
+		case <-ticker.C:
+		case <-b.wake:
+		case m, ok := <-client.Messages():
+			if !ok {
+				return true, client.Err()
+			}
+			// retained messages are stale commands
+			if m.Retain {
+				continue
+			}
+			if err := b.handleCommand(ctx, m); err != nil {
+				log.Printf("mqttBridge.handleCommand(%s): %s\n", m.Topic, err.Error())
+			}
+		}
+	}
+}
+
+func (b *mqttBridge) publishStatus(ctx context.Context, client *mqtt.Client) error {
+	stat, err := b.budgetDomain.GetStat(ctx)
+	if errors.Is(err, db.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("budgetDomain.GetStat: %w", err)
+	}
+
+	data, err := json.Marshal(stat)
+	if err != nil {
+		return fmt.Errorf("json.Marshal: %w", err)
+	}
+
+	topics := []struct {
+		name  string
+		value string
+	}{
+		{"stat", string(data)},
+		{"balance", formatMQTTValue(stat.TotalBalance)},
+		{"deviation", formatMQTTValue(stat.BalanceDeviation)},
+		{"days_left", formatMQTTValue(stat.BudgetDaysToExpiration)},
+		{"today_allowance", formatMQTTValue(stat.TodayAllowance)},
+		{"daily_average", formatMQTTValue(stat.DailyAverageSpending)},
+		{"budget", formatMQTTValue(stat.BudgetAmount)},
+		{"cash", formatMQTTValue(stat.CashBalance)},
+		{"reserved", formatMQTTValue(stat.ReservedBalance)},
+	}
+	for _, t := range topics {
+		if err := client.Publish(mqtt.Message{Topic: b.prefix + "/" + t.name, Payload: []byte(t.value), Retain: true}); err != nil {
+			return fmt.Errorf("Publish: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// handleCommand applies <prefix>/cmd/cash, cmd/cash/add, cmd/card and cmd/reserve with a number payload
+func (b *mqttBridge) handleCommand(ctx context.Context, m mqtt.Message) error {
+	val, err := strconv.ParseFloat(strings.TrimSpace(string(m.Payload)), 64)
+	if err != nil {
+		return fmt.Errorf("invalid value %q", m.Payload)
+	}
+
+	switch strings.TrimPrefix(m.Topic, b.prefix+"/cmd/") {
+	case "cash":
+		return b.budgetDomain.SetCash(ctx, val)
+	case "cash/add":
+		return b.budgetDomain.AddCash(ctx, val)
+	case "card":
+		return b.budgetDomain.UpdateAccountBalance(ctx, val)
+	case "reserve":
+		return b.budgetDomain.SetReservedValue(ctx, val)
+	}
+
+	return fmt.Errorf("unknown command")
+}
+
+func formatMQTTValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
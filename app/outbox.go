@@ -0,0 +1,193 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/i18n"
+	"github.com/unkeep/alfabooker/tg"
+)
+
+const (
+	// outboxMaxAttempts is a number of attempts after which a message is considered undeliverable
+	outboxMaxAttempts = 10
+	// outboxChatInterval is a minimal interval between messages to the same chat
+	outboxChatInterval = time.Second
+	// outboxLease is how long a claimed message is not claimed again
+	outboxLease = time.Minute
+	// outboxPollInterval is how often due messages are checked without explicit wakeups
+	outboxPollInterval = time.Second * 15
+)
+
+// outbox delivers queued telegram messages with retries
+type outbox struct {
+	repo  *db.OutboxRepo
+	tgBot *tg.Bot
+
+	wake chan struct{}
+
+	mu       sync.Mutex
+	lastSent map[int64]time.Time
+}
+
+func newOutbox(repo *db.OutboxRepo, tgBot *tg.Bot) *outbox {
+	return &outbox{
+		repo:     repo,
+		tgBot:    tgBot,
+		wake:     make(chan struct{}, 1),
+		lastSent: map[int64]time.Time{},
+	}
+}
+
+// enqueue persists the message and triggers its delivery
+func (o *outbox) enqueue(ctx context.Context, m tg.BotMessage) error {
+	now := time.Now().Unix()
+	msg := db.OutMsg{
+		ChatID:        m.ChatID,
+		Text:          m.Text,
+		TextMarkdown:  m.TextMarkdown,
+		Status:        db.OutMsgPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+	if err := o.repo.Add(ctx, msg); err != nil {
+		return fmt.Errorf("OutboxRepo.Add: %w", err)
+	}
+
+	select {
+	case o.wake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// run delivers due messages until ctx is done
+func (o *outbox) run(ctx context.Context) {
+	ticker := time.NewTicker(outboxPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := o.deliverDue(ctx); err != nil {
+			log.Println("outbox.deliverDue:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-o.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (o *outbox) deliverDue(ctx context.Context) error {
+	for ctx.Err() == nil {
+		now := time.Now()
+		msg, err := o.repo.ClaimDue(ctx, now.Unix(), now.Add(outboxLease).Unix())
+		if err == db.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("OutboxRepo.ClaimDue: %w", err)
+		}
+
+		o.deliver(ctx, &msg)
+
+		if err := o.repo.Save(ctx, msg); err != nil {
+			return fmt.Errorf("OutboxRepo.Save: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (o *outbox) deliver(ctx context.Context, msg *db.OutMsg) {
+	o.waitChatInterval(ctx, msg.ChatID)
+
+	msgID, err := o.tgBot.SendMessage(tg.BotMessage{
+		ChatID:       msg.ChatID,
+		Text:         msg.Text,
+		TextMarkdown: msg.TextMarkdown,
+	})
+
+	o.mu.Lock()
+	o.lastSent[msg.ChatID] = time.Now()
+	o.mu.Unlock()
+
+	msg.Attempts++
+	if err == nil {
+		msg.Status = db.OutMsgSent
+		msg.SentAt = time.Now().Unix()
+		msg.MessageID = msgID
+		msg.LastError = ""
+		return
+	}
+
+	log.Printf("outbox: attempt %d to deliver %s failed: %s\n", msg.Attempts, msg.ID.Hex(), err.Error())
+	msg.LastError = err.Error()
+	if msg.Attempts >= outboxMaxAttempts || tg.IsBadRequest(err) {
+		msg.Status = db.OutMsgFailed
+		return
+	}
+	msg.NextAttemptAt = time.Now().Add(retryDelay(msg.Attempts, err)).Unix()
+}
+
+// waitChatInterval waits until a message can be sent to the chat without exceeding the per chat rate limit
+func (o *outbox) waitChatInterval(ctx context.Context, chatID int64) {
+	o.mu.Lock()
+	wait := outboxChatInterval - time.Since(o.lastSent[chatID])
+	o.mu.Unlock()
+
+	if wait <= 0 {
+		return
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(wait):
+	}
+}
+
+// retryDelay returns a delay before the next attempt, honouring Telegram's retry_after
+func retryDelay(attempts int, err error) time.Duration {
+	if d, ok := tg.RetryAfter(err); ok {
+		return d
+	}
+
+	delay := time.Second << uint(attempts)
+	if delay > time.Minute*10 || delay <= 0 {
+		delay = time.Minute * 10
+	}
+
+	return delay
+}
+
+func (c *controller) showOutboxStatus(ctx context.Context, chatID int64) error {
+	pending, err := c.repo.Outbox.CountByStatus(ctx, db.OutMsgPending)
+	if err != nil {
+		return fmt.Errorf("Outbox.CountByStatus: %w", err)
+	}
+
+	failed, err := c.repo.Outbox.CountByStatus(ctx, db.OutMsgFailed)
+	if err != nil {
+		return fmt.Errorf("Outbox.CountByStatus: %w", err)
+	}
+
+	lastFailed, err := c.repo.Outbox.GetLastFailed(ctx, 5)
+	if err != nil {
+		return fmt.Errorf("Outbox.GetLastFailed: %w", err)
+	}
+
+	p := c.printer(ctx, chatID)
+	text := p.T(i18n.OutboxStatus, pending, failed)
+	for _, m := range lastFailed {
+		text += "\n" + p.T(i18n.OutboxFailed, p.DateTime(time.Unix(m.CreatedAt, 0)), m.Attempts, m.LastError)
+	}
+
+	return c.sendText(chatID, text)
+}
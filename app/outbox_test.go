@@ -0,0 +1,30 @@
+package app
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		attempts int
+		err      error
+		want     time.Duration
+	}{
+		{"backoff", 3, errors.New("timeout"), time.Second * 8},
+		{"max backoff", 20, errors.New("timeout"), time.Minute * 10},
+		{"retry after", 3, &tgbotapi.Error{Code: 429, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 42}}, time.Second * 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if d := retryDelay(tt.attempts, tt.err); d != tt.want {
+				t.Errorf("got %s, want %s", d, tt.want)
+			}
+		})
+	}
+}
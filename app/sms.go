@@ -0,0 +1,104 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/i18n"
+	"github.com/unkeep/alfabooker/tg"
+)
+
+const flowSMS = "sms"
+
+// handlePastedSMS applies a bank SMS pasted or forwarded to the chat, an ambiguous SMS is applied only after
+// a confirmation, other texts are ignored
+func (c *controller) handlePastedSMS(ctx context.Context, chatID int64, text string) error {
+	preview, err := c.budgetDomain.PreviewSMS(text)
+	if err != nil {
+		return nil
+	}
+
+	b, err := c.budgetDomain.GetBudget(ctx)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return fmt.Errorf("budgetDomain.GetBudget: %w", err)
+	}
+
+	p := c.printer(ctx, chatID)
+	var doubts []string
+	if preview.At == 0 {
+		doubts = append(doubts, p.T(i18n.SMSNoTime))
+	} else if preview.At < b.BalanceAt {
+		doubts = append(doubts, p.T(i18n.SMSOlder, p.DateTime(time.Unix(b.BalanceAt, 0))))
+	}
+	if !preview.HasAmount {
+		doubts = append(doubts, p.T(i18n.SMSNoAmount))
+	}
+
+	if len(doubts) == 0 {
+		return c.applySMS(ctx, chatID, text)
+	}
+
+	conv := db.Conversation{
+		ChatID: chatID,
+		Flow:   flowSMS,
+		Step:   stepConfirm,
+		Text:   text,
+	}
+	question := p.T(i18n.SMSConfirm, describeSMS(p, preview), strings.Join(doubts, "\n"))
+	btns := []tg.Btn{{ID: btnConfirm, Text: p.T(i18n.BtnConfirm)}, {ID: btnCancel, Text: p.T(i18n.BtnCancel)}}
+
+	return c.sendWizardPrompt(ctx, conv, question, btns)
+}
+
+func finishSMS(ctx context.Context, c *controller, conv db.Conversation) error {
+	return c.applySMS(ctx, conv.ChatID, conv.Text)
+}
+
+// applySMS applies the SMS and replies with the recorded transaction
+func (c *controller) applySMS(ctx context.Context, chatID int64, text string) error {
+	res, err := c.budgetDomain.ApplySMS(ctx, budget.SMS{Text: text})
+	if err != nil {
+		return fmt.Errorf("budgetDomain.ApplySMS: %w", err)
+	}
+
+	p := c.printer(ctx, chatID)
+	switch res.Status {
+	case budget.SMSDuplicate:
+		return c.notify(ctx, chatID, p.T(i18n.SMSKnown))
+	case budget.SMSOutdated:
+		return c.notify(ctx, chatID, p.T(i18n.SMSRecorded, p.SignedNumber(res.Amount), p.DateTime(time.Unix(res.At, 0))))
+	}
+
+	stat, err := c.budgetDomain.GetStat(ctx)
+	if err != nil {
+		return fmt.Errorf("budgetDomain.GetStat: %w", err)
+	}
+
+	return c.notify(ctx, chatID, p.T(i18n.SMSApplied,
+		p.SignedNumber(res.Amount),
+		p.DateTime(time.Unix(res.At, 0)),
+		p.Number(stat.AccountBalance),
+		p.Number(stat.TotalBalance),
+	))
+}
+
+func describeSMS(p i18n.Printer, preview budget.SMSPreview) string {
+	parts := []string{}
+	if preview.HasAmount {
+		parts = append(parts, p.Decimal(preview.Amount, 2)+" "+preview.Currency)
+	}
+	if preview.Merchant != "" {
+		parts = append(parts, preview.Merchant)
+	}
+	if preview.At != 0 {
+		parts = append(parts, p.DateTime(time.Unix(preview.At, 0)))
+	}
+	parts = append(parts, p.T(i18n.SMSBalance, p.Decimal(preview.Balance, 2)))
+
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,84 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/i18n"
+	"github.com/unkeep/alfabooker/statement"
+	"github.com/unkeep/alfabooker/tg"
+)
+
+// maxStatementSize limits statement files downloaded from Telegram
+const maxStatementSize = 10 << 20
+
+// maxReportLines limits listed rows of each kind in a reconciliation report
+const maxReportLines = 20
+
+// importStatement reconciles a statement file sent to the chat, the result is reported by reportReconciliation
+func (c *controller) importStatement(ctx context.Context, chatID int64, doc *tg.Document) error {
+	p := c.printer(ctx, chatID)
+
+	if doc.Size > maxStatementSize {
+		return c.sendText(chatID, p.T(i18n.StatementUnsupported))
+	}
+
+	data, err := c.tgBot.DownloadFile(doc.FileID, maxStatementSize)
+	if err != nil {
+		return fmt.Errorf("tgBot.DownloadFile: %w", err)
+	}
+
+	format, ok := statement.DetectFormat(doc.Name, data)
+	if !ok {
+		return c.sendText(chatID, p.T(i18n.StatementUnsupported))
+	}
+
+	rows, err := statement.Parse(format, data)
+	if err != nil {
+		return c.sendText(chatID, p.T(i18n.StatementInvalid, err.Error()))
+	}
+
+	if _, err := c.budgetDomain.ReconcileStatement(ctx, rows); err != nil {
+		return fmt.Errorf("budgetDomain.ReconcileStatement: %w", err)
+	}
+
+	return nil
+}
+
+// reportReconciliation sends a statement reconciliation result to the admin chat
+func (c *controller) reportReconciliation(ctx context.Context, rec budget.Reconciliation) error {
+	chatID := c.cfg.TgAdminChatID
+	p := c.printer(ctx, chatID)
+
+	lines := []string{p.T(i18n.ReconcileReport, rec.Rows, rec.Matched, len(rec.Added), len(rec.Mismatches), len(rec.Unmatched))}
+	date := func(at int64) string {
+		return p.Date(time.Unix(at, 0))
+	}
+
+	for i, r := range rec.Added {
+		if i == maxReportLines {
+			lines = append(lines, "…")
+			break
+		}
+		lines = append(lines, p.T(i18n.ReconcileAdded, date(r.At), p.Decimal(r.Amount, 2), r.Merchant))
+	}
+	for i, m := range rec.Mismatches {
+		if i == maxReportLines {
+			lines = append(lines, "…")
+			break
+		}
+		lines = append(lines, p.T(i18n.ReconcileMismatch, date(m.Row.At), m.Row.Merchant, p.Decimal(m.Row.Amount, 2), p.Decimal(m.Transaction.Amount, 2)))
+	}
+	for i, tx := range rec.Unmatched {
+		if i == maxReportLines {
+			lines = append(lines, "…")
+			break
+		}
+		lines = append(lines, p.T(i18n.ReconcileUnmatched, date(tx.At), p.Decimal(tx.Amount, 2), tx.Merchant))
+	}
+
+	return c.notify(ctx, chatID, strings.Join(lines, "\n"))
+}
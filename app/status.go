@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/i18n"
+	"github.com/unkeep/alfabooker/tg"
+)
+
+func (c *controller) enableStatus(ctx context.Context, chatID int64) error {
+	chat, err := c.repo.Chats.Get(ctx, chatID)
+	if err != nil && err != db.ErrNotFound {
+		return fmt.Errorf("Chats.Get: %w", err)
+	}
+	chat.ID = chatID
+	chat.StatusEnabled = true
+	// a new message is pinned at the bottom of the chat
+	chat.StatusMsgID = 0
+
+	return c.refreshStatusMessage(ctx, chat)
+}
+
+func (c *controller) disableStatus(ctx context.Context, chatID int64) error {
+	chat, err := c.repo.Chats.Get(ctx, chatID)
+	if err != nil && err != db.ErrNotFound {
+		return fmt.Errorf("Chats.Get: %w", err)
+	}
+	chat.ID = chatID
+	chat.StatusEnabled = false
+
+	if err := c.repo.Chats.Save(ctx, chat); err != nil {
+		return fmt.Errorf("Chats.Save: %w", err)
+	}
+
+	return c.sendText(chatID, c.printer(ctx, chatID).T(i18n.StatusDisabled))
+}
+
+// refreshStatusMessages updates status messages of all chats having them
+func (c *controller) refreshStatusMessages(ctx context.Context) error {
+	chats, err := c.repo.Chats.GetWithStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("Chats.GetWithStatus: %w", err)
+	}
+
+	for _, chat := range chats {
+		if err := c.refreshStatusMessage(ctx, chat); err != nil {
+			return fmt.Errorf("refreshStatusMessage(%d): %w", chat.ID, err)
+		}
+	}
+
+	return nil
+}
+
+// refreshStatusMessage edits the status message of the chat or sends and pins a new one if there is no message
+func (c *controller) refreshStatusMessage(ctx context.Context, chat db.Chat) error {
+	stat, err := c.budgetDomain.GetStat(ctx)
+	if err != nil {
+		return fmt.Errorf("budgetDomain.GetStat: %w", err)
+	}
+
+	p := c.printer(ctx, chat.ID)
+	msg := tg.BotMessage{
+		ChatID: chat.ID,
+		Text: p.T(i18n.Status,
+			p.Number(stat.TotalBalance),
+			p.SignedNumber(stat.BalanceDeviation),
+			p.Decimal(stat.BudgetDaysToExpiration, 1),
+			p.Number(stat.TodayAllowance),
+			p.DateTime(time.Now()),
+		),
+	}
+
+	if chat.StatusMsgID != 0 {
+		err := c.tgBot.EditMessage(msg, chat.StatusMsgID)
+		if err == nil || tg.IsMessageNotModified(err) {
+			return nil
+		}
+		if !tg.IsMessageNotFound(err) {
+			return fmt.Errorf("tgBot.EditMessage: %w", err)
+		}
+		// the message has been deleted, a new one is created
+	}
+
+	msgID, err := c.tgBot.SendMessage(msg)
+	if err != nil {
+		return fmt.Errorf("tgBot.SendMessage: %w", err)
+	}
+
+	if err := c.tgBot.PinMessage(chat.ID, msgID); err != nil {
+		return fmt.Errorf("tgBot.PinMessage: %w", err)
+	}
+
+	chat.StatusMsgID = msgID
+	if err := c.repo.Chats.Save(ctx, chat); err != nil {
+		return fmt.Errorf("Chats.Save: %w", err)
+	}
+
+	return nil
+}
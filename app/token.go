@@ -0,0 +1,95 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/auth"
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/i18n"
+)
+
+// handleTokenCommand handles "/token new <name> <scopes> [days]", "/token list" and "/token revoke <name>"
+func (c *controller) handleTokenCommand(ctx context.Context, chatID int64, args []string) error {
+	p := c.printer(ctx, chatID)
+
+	if len(args) == 0 {
+		return c.sendText(chatID, p.T(i18n.TokenUsage, strings.Join(auth.Scopes, ", ")))
+	}
+
+	switch {
+	case args[0] == "new" && (len(args) == 3 || len(args) == 4):
+		return c.issueToken(ctx, chatID, args[1], args[2], args[3:])
+	case args[0] == "list" && len(args) == 1:
+		return c.listTokens(ctx, chatID)
+	case args[0] == "revoke" && len(args) == 2:
+		err := c.auth.Revoke(ctx, args[1])
+		if errors.Is(err, db.ErrNotFound) {
+			return c.sendText(chatID, p.T(i18n.TokenNotFound, args[1]))
+		}
+		if err != nil {
+			return fmt.Errorf("auth.Revoke: %w", err)
+		}
+		return c.sendText(chatID, p.T(i18n.TokenRevoked, args[1]))
+	}
+
+	return c.sendText(chatID, p.T(i18n.TokenUsage, strings.Join(auth.Scopes, ", ")))
+}
+
+func (c *controller) issueToken(ctx context.Context, chatID int64, name, scopesArg string, daysArg []string) error {
+	p := c.printer(ctx, chatID)
+
+	scopes, err := auth.ParseScopes(scopesArg)
+	if err != nil {
+		return c.sendText(chatID, p.T(i18n.TokenUsage, strings.Join(auth.Scopes, ", ")))
+	}
+
+	var ttl time.Duration
+	if len(daysArg) > 0 {
+		days, err := strconv.Atoi(daysArg[0])
+		if err != nil || days <= 0 {
+			return c.sendText(chatID, p.T(i18n.TokenUsage, strings.Join(auth.Scopes, ", ")))
+		}
+		ttl = time.Hour * 24 * time.Duration(days)
+	}
+
+	secret, err := c.auth.Issue(ctx, name, scopes, ttl)
+	if err == auth.ErrTokenExists {
+		return c.sendText(chatID, p.T(i18n.TokenExists, name))
+	}
+	if err != nil {
+		return fmt.Errorf("auth.Issue: %w", err)
+	}
+
+	return c.sendText(chatID, p.T(i18n.TokenIssued, name, strings.Join(scopes, ","), secret))
+}
+
+func (c *controller) listTokens(ctx context.Context, chatID int64) error {
+	tokens, err := c.auth.List(ctx)
+	if err != nil {
+		return fmt.Errorf("auth.List: %w", err)
+	}
+
+	p := c.printer(ctx, chatID)
+	if len(tokens) == 0 {
+		return c.sendText(chatID, p.T(i18n.TokenNone))
+	}
+
+	lines := make([]string, 0, len(tokens))
+	for _, t := range tokens {
+		expires, lastUsed := "-", "-"
+		if t.ExpiresAt != 0 {
+			expires = p.Date(time.Unix(t.ExpiresAt, 0))
+		}
+		if t.LastUsedAt != 0 {
+			lastUsed = p.DateTime(time.Unix(t.LastUsedAt, 0))
+		}
+		lines = append(lines, p.T(i18n.TokenListItem, t.Name, strings.Join(t.Scopes, ","), expires, lastUsed))
+	}
+
+	return c.sendText(chatID, strings.Join(lines, "\n"))
+}
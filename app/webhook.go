@@ -0,0 +1,123 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/i18n"
+	"github.com/unkeep/alfabooker/webhook"
+)
+
+// webhookLogSize is a number of deliveries shown by "/webhook log"
+const webhookLogSize = 10
+
+// handleWebhookCommand handles "/webhook add <url> [events]", "/webhook list", "/webhook remove <id>" and "/webhook log"
+func (c *controller) handleWebhookCommand(ctx context.Context, chatID int64, args []string) error {
+	p := c.printer(ctx, chatID)
+	usage := p.T(i18n.WebhookUsage, eventNames())
+
+	switch {
+	case len(args) >= 2 && len(args) <= 3 && args[0] == "add":
+		return c.addWebhook(ctx, chatID, args[1], args[2:])
+	case len(args) == 1 && args[0] == "list":
+		return c.listWebhooks(ctx, chatID)
+	case len(args) == 2 && args[0] == "remove":
+		err := c.webhooks.Remove(ctx, args[1])
+		if errors.Is(err, db.ErrNotFound) {
+			return c.sendText(chatID, p.T(i18n.WebhookNotFound, args[1]))
+		}
+		if err != nil {
+			return fmt.Errorf("webhooks.Remove: %w", err)
+		}
+		return c.sendText(chatID, p.T(i18n.WebhookRemoved, args[1]))
+	case len(args) == 1 && args[0] == "log":
+		return c.showWebhookLog(ctx, chatID)
+	}
+
+	return c.sendText(chatID, usage)
+}
+
+func (c *controller) addWebhook(ctx context.Context, chatID int64, url string, eventsArg []string) error {
+	p := c.printer(ctx, chatID)
+
+	var events []string
+	if len(eventsArg) > 0 {
+		var err error
+		events, err = webhook.ParseEvents(strings.ToLower(eventsArg[0]))
+		if err != nil {
+			return c.sendText(chatID, p.T(i18n.WebhookUsage, eventNames()))
+		}
+	}
+
+	w, err := c.webhooks.Add(ctx, url, events)
+	if err == webhook.ErrInvalidURL {
+		return c.sendText(chatID, p.T(i18n.WebhookUsage, eventNames()))
+	}
+	if err != nil {
+		return fmt.Errorf("webhooks.Add: %w", err)
+	}
+
+	return c.sendText(chatID, p.T(i18n.WebhookAdded, w.ID.Hex(), w.URL, webhookEvents(p, w), w.Secret))
+}
+
+func (c *controller) listWebhooks(ctx context.Context, chatID int64) error {
+	hooks, err := c.webhooks.List(ctx)
+	if err != nil {
+		return fmt.Errorf("webhooks.List: %w", err)
+	}
+
+	p := c.printer(ctx, chatID)
+	if len(hooks) == 0 {
+		return c.sendText(chatID, p.T(i18n.WebhookNone))
+	}
+
+	lines := make([]string, 0, len(hooks))
+	for _, w := range hooks {
+		lines = append(lines, p.T(i18n.WebhookListItem, w.ID.Hex(), w.URL, webhookEvents(p, w)))
+	}
+
+	return c.sendText(chatID, strings.Join(lines, "\n"))
+}
+
+func (c *controller) showWebhookLog(ctx context.Context, chatID int64) error {
+	deliveries, err := c.webhooks.Log(ctx, webhookLogSize)
+	if err != nil {
+		return fmt.Errorf("webhooks.Log: %w", err)
+	}
+
+	p := c.printer(ctx, chatID)
+	if len(deliveries) == 0 {
+		return c.sendText(chatID, p.T(i18n.WebhookLogEmpty))
+	}
+
+	lines := make([]string, 0, len(deliveries))
+	for _, d := range deliveries {
+		line := p.T(i18n.WebhookLogItem, p.DateTime(time.Unix(d.CreatedAt, 0)), d.Event, d.URL, d.Status, d.Attempts)
+		if d.LastError != "" {
+			line += ": " + d.LastError
+		}
+		lines = append(lines, line)
+	}
+
+	return c.sendText(chatID, strings.Join(lines, "\n"))
+}
+
+func webhookEvents(p i18n.Printer, w db.Webhook) string {
+	if len(w.Events) == 0 {
+		return p.T(i18n.WebhookAllEvents)
+	}
+	return strings.Join(w.Events, ",")
+}
+
+func eventNames() string {
+	names := make([]string, 0, len(budget.EventTypes))
+	for _, t := range budget.EventTypes {
+		names = append(names, string(t))
+	}
+	return strings.Join(names, ", ")
+}
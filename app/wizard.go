@@ -0,0 +1,355 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/i18n"
+	"github.com/unkeep/alfabooker/tg"
+)
+
+const (
+	flowNewBudget = "new_budget"
+	flowReconcile = "reconcile"
+)
+
+const (
+	stepConfirm = "confirm"
+
+	btnCancel      = "wz:cancel"
+	btnConfirm     = "wz:confirm"
+	btnValuePrefix = "wz:val:"
+)
+
+// conversations not touched for longer are considered abandoned
+const conversationTTL = time.Hour
+
+type wizardStep struct {
+	name   string
+	prompt func(ctx context.Context, c *controller, conv db.Conversation) (string, []tg.Btn, error)
+	// validate returns a message key for the user if the value is not acceptable, nil validate accepts any value
+	validate func(val float64) (i18n.Key, bool)
+}
+
+type wizardFlow struct {
+	steps []wizardStep
+	// confirm returns a confirmation question, nil means no confirmation is required
+	confirm func(p i18n.Printer, conv db.Conversation) string
+	finish  func(ctx context.Context, c *controller, conv db.Conversation) error
+}
+
+func getWizardFlow(name string) (wizardFlow, bool) {
+	switch name {
+	case flowNewBudget:
+		return wizardFlow{
+			steps: []wizardStep{
+				{name: "days", prompt: promptBudgetDays, validate: validateDays},
+				{name: "amount", prompt: promptBudgetAmount},
+				{name: "reserve", prompt: promptBudgetReserve},
+			},
+			confirm: confirmNewBudget,
+			finish:  finishNewBudget,
+		}, true
+	case flowSMS:
+		// started already at the confirmation by handlePastedSMS
+		return wizardFlow{finish: finishSMS}, true
+	case flowReconcile:
+		return wizardFlow{
+			steps: []wizardStep{
+				{name: "cash", prompt: promptCashOnHand},
+				{name: "card", prompt: promptCardBalance},
+			},
+			finish: finishReconcile,
+		}, true
+	default:
+		return wizardFlow{}, false
+	}
+}
+
+func (c *controller) startWizard(ctx context.Context, chatID int64, flowName string) error {
+	flow, ok := getWizardFlow(flowName)
+	if !ok {
+		return fmt.Errorf("unknown flow: %s", flowName)
+	}
+
+	conv := db.Conversation{
+		ChatID: chatID,
+		Flow:   flowName,
+		Step:   flow.steps[0].name,
+		Values: map[string]float64{},
+	}
+
+	return c.askWizardStep(ctx, conv, flow.steps[0])
+}
+
+// getActiveConversation returns a conversation of the chat if there is a non-abandoned one
+func (c *controller) getActiveConversation(ctx context.Context, chatID int64) (db.Conversation, bool, error) {
+	conv, err := c.repo.Conversations.Get(ctx, chatID)
+	if err == db.ErrNotFound {
+		return conv, false, nil
+	}
+	if err != nil {
+		return conv, false, fmt.Errorf("Conversations.Get: %w", err)
+	}
+
+	if time.Since(time.Unix(conv.UpdatedAt, 0)) > conversationTTL {
+		return conv, false, nil
+	}
+
+	return conv, true, nil
+}
+
+func (c *controller) handleWizardText(ctx context.Context, conv db.Conversation, text string) error {
+	p := c.printer(ctx, conv.ChatID)
+	if conv.Step == stepConfirm {
+		return c.sendText(conv.ChatID, p.T(i18n.WizardUseButtons))
+	}
+
+	val, err := strconv.ParseFloat(strings.ReplaceAll(text, ",", "."), 64)
+	if err != nil {
+		return c.sendText(conv.ChatID, p.T(i18n.WizardEnterNumber))
+	}
+
+	return c.handleWizardValue(ctx, conv, val)
+}
+
+func (c *controller) handleWizardBtnClick(ctx context.Context, click tg.BtnClick) error {
+	conv, ok, err := c.getActiveConversation(ctx, click.ChatID)
+	if err != nil {
+		return fmt.Errorf("getActiveConversation: %w", err)
+	}
+
+	if !ok || conv.MsgID != click.MessageID {
+		// a button of an outdated prompt, the buttons may be already removed
+		if err := c.tgBot.EditBtns(click.ChatID, click.MessageID, nil); err != nil {
+			log.Println("tgBot.EditBtns of outdated prompt:", err)
+		}
+		return nil
+	}
+
+	switch {
+	case click.BtnID == btnCancel:
+		return c.cancelWizard(ctx, conv)
+	case click.BtnID == btnConfirm && conv.Step == stepConfirm:
+		return c.finishWizard(ctx, conv)
+	case strings.HasPrefix(click.BtnID, btnValuePrefix):
+		val, err := strconv.ParseFloat(strings.TrimPrefix(click.BtnID, btnValuePrefix), 64)
+		if err != nil {
+			return fmt.Errorf("parse btn value: %w", err)
+		}
+		return c.handleWizardValue(ctx, conv, val)
+	}
+
+	return nil
+}
+
+func (c *controller) handleWizardValue(ctx context.Context, conv db.Conversation, val float64) error {
+	flow, ok := getWizardFlow(conv.Flow)
+	if !ok {
+		return fmt.Errorf("unknown flow: %s", conv.Flow)
+	}
+
+	for i, step := range flow.steps {
+		if step.name != conv.Step {
+			continue
+		}
+
+		if step.validate != nil {
+			if problem, ok := step.validate(val); !ok {
+				return c.sendText(conv.ChatID, c.printer(ctx, conv.ChatID).T(problem))
+			}
+		}
+		if conv.Values == nil {
+			conv.Values = map[string]float64{}
+		}
+		conv.Values[conv.Step] = val
+
+		if err := c.tgBot.EditBtns(conv.ChatID, conv.MsgID, nil); err != nil {
+			return fmt.Errorf("tgBot.EditBtns: %w", err)
+		}
+
+		if i+1 < len(flow.steps) {
+			return c.askWizardStep(ctx, conv, flow.steps[i+1])
+		}
+
+		if flow.confirm == nil {
+			return c.finishWizard(ctx, conv)
+		}
+
+		p := c.printer(ctx, conv.ChatID)
+		conv.Step = stepConfirm
+		btns := []tg.Btn{{ID: btnConfirm, Text: p.T(i18n.BtnConfirm)}, {ID: btnCancel, Text: p.T(i18n.BtnCancel)}}
+		return c.sendWizardPrompt(ctx, conv, flow.confirm(p, conv), btns)
+	}
+
+	return fmt.Errorf("unknown step %s of flow %s", conv.Step, conv.Flow)
+}
+
+func (c *controller) askWizardStep(ctx context.Context, conv db.Conversation, step wizardStep) error {
+	text, btns, err := step.prompt(ctx, c, conv)
+	if err != nil {
+		return fmt.Errorf("prompt %s: %w", step.name, err)
+	}
+
+	conv.Step = step.name
+	btns = append(btns, tg.Btn{ID: btnCancel, Text: c.printer(ctx, conv.ChatID).T(i18n.BtnCancel)})
+
+	return c.sendWizardPrompt(ctx, conv, text, btns)
+}
+
+func (c *controller) sendWizardPrompt(ctx context.Context, conv db.Conversation, text string, btns []tg.Btn) error {
+	msgID, err := c.tgBot.SendMessage(tg.BotMessage{
+		ChatID: conv.ChatID,
+		Text:   text,
+		Btns:   btns,
+	})
+	if err != nil {
+		return fmt.Errorf("tgBot.SendMessage: %w", err)
+	}
+
+	conv.MsgID = msgID
+	conv.UpdatedAt = time.Now().Unix()
+	if err := c.repo.Conversations.Save(ctx, conv); err != nil {
+		return fmt.Errorf("Conversations.Save: %w", err)
+	}
+
+	return nil
+}
+
+func (c *controller) finishWizard(ctx context.Context, conv db.Conversation) error {
+	flow, ok := getWizardFlow(conv.Flow)
+	if !ok {
+		return fmt.Errorf("unknown flow: %s", conv.Flow)
+	}
+
+	if err := c.tgBot.EditBtns(conv.ChatID, conv.MsgID, nil); err != nil {
+		return fmt.Errorf("tgBot.EditBtns: %w", err)
+	}
+
+	if err := c.repo.Conversations.Delete(ctx, conv.ChatID); err != nil {
+		return fmt.Errorf("Conversations.Delete: %w", err)
+	}
+
+	if err := flow.finish(ctx, c, conv); err != nil {
+		return fmt.Errorf("finish %s: %w", conv.Flow, err)
+	}
+
+	return c.showBudgetStat(ctx, conv.ChatID)
+}
+
+func (c *controller) cancelWizard(ctx context.Context, conv db.Conversation) error {
+	if conv.MsgID != 0 {
+		if err := c.tgBot.EditBtns(conv.ChatID, conv.MsgID, nil); err != nil {
+			return fmt.Errorf("tgBot.EditBtns: %w", err)
+		}
+	}
+
+	if err := c.repo.Conversations.Delete(ctx, conv.ChatID); err != nil {
+		return fmt.Errorf("Conversations.Delete: %w", err)
+	}
+
+	return c.sendText(conv.ChatID, c.printer(ctx, conv.ChatID).T(i18n.WizardCancelled))
+}
+
+func valueBtn(val float64) tg.Btn {
+	return tg.Btn{
+		ID:   btnValuePrefix + strconv.FormatFloat(val, 'f', -1, 64),
+		Text: fmt.Sprint(int(val)),
+	}
+}
+
+func promptBudgetDays(ctx context.Context, c *controller, conv db.Conversation) (string, []tg.Btn, error) {
+	text := c.printer(ctx, conv.ChatID).T(i18n.WizardBudgetDays)
+
+	return text, []tg.Btn{valueBtn(7), valueBtn(14), valueBtn(30)}, nil
+}
+
+func validateDays(val float64) (i18n.Key, bool) {
+	if val < 1 || val != float64(int(val)) {
+		return i18n.WizardInvalidDays, false
+	}
+	return "", true
+}
+
+func promptBudgetAmount(ctx context.Context, c *controller, conv db.Conversation) (string, []tg.Btn, error) {
+	b, err := c.repo.Budget.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return "", nil, fmt.Errorf("Budget.Get: %w", err)
+	}
+	available := b.Balance + b.CashBalance - b.ReservedValue
+
+	p := c.printer(ctx, conv.ChatID)
+	text := p.T(i18n.WizardBudgetAmount, p.Number(available))
+
+	return text, []tg.Btn{valueBtn(available)}, nil
+}
+
+func promptBudgetReserve(ctx context.Context, c *controller, conv db.Conversation) (string, []tg.Btn, error) {
+	b, err := c.repo.Budget.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return "", nil, fmt.Errorf("Budget.Get: %w", err)
+	}
+
+	btns := []tg.Btn{valueBtn(0)}
+	if b.ReservedValue != 0 {
+		btns = append([]tg.Btn{valueBtn(b.ReservedValue)}, btns...)
+	}
+
+	p := c.printer(ctx, conv.ChatID)
+
+	return p.T(i18n.WizardBudgetReserve, p.Number(b.ReservedValue)), btns, nil
+}
+
+func confirmNewBudget(p i18n.Printer, conv db.Conversation) string {
+	return p.T(i18n.WizardBudgetConfirm,
+		p.Number(conv.Values["amount"]), p.Number(conv.Values["days"]), p.Number(conv.Values["reserve"]))
+}
+
+func finishNewBudget(ctx context.Context, c *controller, conv db.Conversation) error {
+	err := c.budgetDomain.StartCustomBudget(ctx,
+		int(conv.Values["days"]), conv.Values["amount"], conv.Values["reserve"])
+	if err != nil {
+		return fmt.Errorf("budgetDomain.StartCustomBudget: %w", err)
+	}
+
+	return nil
+}
+
+func promptCashOnHand(ctx context.Context, c *controller, conv db.Conversation) (string, []tg.Btn, error) {
+	b, err := c.repo.Budget.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return "", nil, fmt.Errorf("Budget.Get: %w", err)
+	}
+
+	p := c.printer(ctx, conv.ChatID)
+
+	return p.T(i18n.WizardCashOnHand, p.Number(b.CashBalance)), []tg.Btn{valueBtn(b.CashBalance)}, nil
+}
+
+func promptCardBalance(ctx context.Context, c *controller, conv db.Conversation) (string, []tg.Btn, error) {
+	b, err := c.repo.Budget.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return "", nil, fmt.Errorf("Budget.Get: %w", err)
+	}
+
+	p := c.printer(ctx, conv.ChatID)
+
+	return p.T(i18n.WizardCardBalance, p.Number(b.Balance)), []tg.Btn{valueBtn(b.Balance)}, nil
+}
+
+func finishReconcile(ctx context.Context, c *controller, conv db.Conversation) error {
+	if err := c.budgetDomain.SetCash(ctx, conv.Values["cash"]); err != nil {
+		return fmt.Errorf("budgetDomain.SetCash: %w", err)
+	}
+
+	if err := c.budgetDomain.UpdateAccountBalance(ctx, conv.Values["card"]); err != nil {
+		return fmt.Errorf("budgetDomain.UpdateAccountBalance: %w", err)
+	}
+
+	return nil
+}
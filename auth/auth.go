@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/db"
+)
+
+// Token scopes
+const (
+	// ScopeSMSWrite allows to post bank SMS
+	ScopeSMSWrite = "sms:write"
+	// ScopeStatsRead allows to read budget statistics
+	ScopeStatsRead = "stats:read"
+	// ScopeBudgetWrite allows to change the budget and balances
+	ScopeBudgetWrite = "budget:write"
+	// ScopeWidgetsWrite allows to store widget formats of the token
+	ScopeWidgetsWrite = "widgets:write"
+	// ScopeAdmin allows everything
+	ScopeAdmin = "admin"
+)
+
+// Scopes are all known scopes
+var Scopes = []string{ScopeSMSWrite, ScopeStatsRead, ScopeBudgetWrite, ScopeWidgetsWrite, ScopeAdmin}
+
+// secretPrefix makes tokens recognisable in configs and leaked texts
+const secretPrefix = "ab_"
+
+// ErrUnauthorized is returned for unknown or expired tokens
+var ErrUnauthorized = errors.New("unauthorized")
+
+// ErrTokenExists is returned when issuing a token with a name already in use
+var ErrTokenExists = errors.New("token with this name already exists")
+
+// Principal is an authenticated API client
+type Principal struct {
+	Name   string
+	Scopes []string
+	// Widgets are widget templates defined for the token
+	Widgets map[string]string
+}
+
+// Can reports whether the principal is allowed to act within the scope
+func (p Principal) Can(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseScopes parses a comma separated list of scopes
+func ParseScopes(s string) ([]string, error) {
+	var scopes []string
+	for _, scope := range strings.Split(s, ",") {
+		scope = strings.TrimSpace(scope)
+		if scope == "" {
+			continue
+		}
+
+		known := false
+		for _, k := range Scopes {
+			known = known || k == scope
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown scope: %s", scope)
+		}
+		scopes = append(scopes, scope)
+	}
+
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("no scopes")
+	}
+
+	return scopes, nil
+}
+
+// Service issues and checks API tokens
+type Service struct {
+	repo *db.TokensRepo
+	// legacyToken is a single shared token from the environment having the admin scope
+	legacyToken string
+}
+
+// NewService creates a token service, an empty legacyToken is never accepted
+func NewService(repo *db.TokensRepo, legacyToken string) *Service {
+	return &Service{repo: repo, legacyToken: legacyToken}
+}
+
+// Authenticate returns a principal of the token secret
+func (s *Service) Authenticate(ctx context.Context, secret string) (Principal, error) {
+	if secret == "" {
+		return Principal{}, ErrUnauthorized
+	}
+
+	if s.legacyToken != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(s.legacyToken)) == 1 {
+		return Principal{Name: "env", Scopes: []string{ScopeAdmin}}, nil
+	}
+
+	t, err := s.repo.GetOne(ctx, hashSecret(secret))
+	if err == db.ErrNotFound {
+		return Principal{}, ErrUnauthorized
+	}
+	if err != nil {
+		return Principal{}, fmt.Errorf("TokensRepo.GetOne: %w", err)
+	}
+
+	now := time.Now().Unix()
+	if t.ExpiresAt != 0 && t.ExpiresAt < now {
+		return Principal{}, ErrUnauthorized
+	}
+
+	if err := s.repo.SetLastUsedAt(ctx, t.ID, now); err != nil {
+		log.Println("TokensRepo.SetLastUsedAt:", err)
+	}
+
+	return Principal{Name: t.Name, Scopes: t.Scopes, Widgets: t.Widgets}, nil
+}
+
+// Issue creates a named token and returns its secret, ttl of 0 means no expiration
+func (s *Service) Issue(ctx context.Context, name string, scopes []string, ttl time.Duration) (string, error) {
+	_, err := s.repo.GetByName(ctx, name)
+	if err == nil {
+		return "", ErrTokenExists
+	}
+	if err != db.ErrNotFound {
+		return "", fmt.Errorf("TokensRepo.GetByName: %w", err)
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	t := db.Token{
+		ID:        hashSecret(secret),
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: now.Unix(),
+	}
+	if ttl > 0 {
+		t.ExpiresAt = now.Add(ttl).Unix()
+	}
+
+	if err := s.repo.Save(ctx, t); err != nil {
+		return "", fmt.Errorf("TokensRepo.Save: %w", err)
+	}
+
+	return secret, nil
+}
+
+// List returns all issued tokens
+func (s *Service) List(ctx context.Context) ([]db.Token, error) {
+	tokens, err := s.repo.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("TokensRepo.GetAll: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Revoke deletes a token by its name
+func (s *Service) Revoke(ctx context.Context, name string) error {
+	if err := s.repo.DeleteByName(ctx, name); err != nil {
+		return fmt.Errorf("TokensRepo.DeleteByName: %w", err)
+	}
+
+	return nil
+}
+
+// SetWidget stores a widget template for the named token, an empty template deletes it
+func (s *Service) SetWidget(ctx context.Context, name, format, tmpl string) error {
+	if err := s.repo.SetWidget(ctx, name, format, tmpl); err != nil {
+		return fmt.Errorf("TokensRepo.SetWidget: %w", err)
+	}
+
+	return nil
+}
+
+// newSecret generates a random secret
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("rand.Read: %w", err)
+	}
+
+	return secretPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashSecret(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"context"
+	"testing"
+)
+
+func TestPrincipalCan(t *testing.T) {
+	p := Principal{Scopes: []string{ScopeSMSWrite}}
+	if !p.Can(ScopeSMSWrite) || p.Can(ScopeStatsRead) {
+		t.Errorf("unexpected scopes check of %+v", p)
+	}
+
+	admin := Principal{Scopes: []string{ScopeAdmin}}
+	if !admin.Can(ScopeBudgetWrite) {
+		t.Error("admin must be allowed everything")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := ParseScopes("sms:write, stats:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scopes) != 2 || scopes[0] != ScopeSMSWrite || scopes[1] != ScopeStatsRead {
+		t.Errorf("unexpected scopes %v", scopes)
+	}
+
+	if _, err := ParseScopes("sms:write,root"); err == nil {
+		t.Error("error expected for unknown scope")
+	}
+	if _, err := ParseScopes(" "); err == nil {
+		t.Error("error expected for no scopes")
+	}
+}
+
+func TestAuthenticateLegacyToken(t *testing.T) {
+	s := NewService(nil, "legacy")
+
+	p, err := s.Authenticate(context.Background(), "legacy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !p.Can(ScopeAdmin) {
+		t.Errorf("unexpected principal %+v", p)
+	}
+
+	if _, err := s.Authenticate(context.Background(), ""); err != ErrUnauthorized {
+		t.Errorf("unexpected error %v", err)
+	}
+}
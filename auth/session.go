@@ -0,0 +1,93 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/unkeep/alfabooker/db"
+)
+
+// LoginLinkTTL is how long a login link issued by the bot is valid
+const LoginLinkTTL = 10 * time.Minute
+
+// SessionTTL is how long a web session lasts
+const SessionTTL = 30 * 24 * time.Hour
+
+// Sessions issues one time login links and web sessions they are exchanged for
+type Sessions struct {
+	repo *db.SessionsRepo
+}
+
+// NewSessions creates a sessions service
+func NewSessions(repo *db.SessionsRepo) *Sessions {
+	return &Sessions{repo: repo}
+}
+
+// IssueLoginLink returns a secret of a new login link
+func (s *Sessions) IssueLoginLink(ctx context.Context) (string, error) {
+	return s.issue(ctx, db.SessionLoginLink, LoginLinkTTL)
+}
+
+// Login exchanges a login link secret for a web session secret, the link can not be used again
+func (s *Sessions) Login(ctx context.Context, linkSecret string) (string, error) {
+	if linkSecret == "" {
+		return "", ErrUnauthorized
+	}
+
+	_, err := s.repo.Take(ctx, hashSecret(linkSecret), db.SessionLoginLink)
+	if err == db.ErrNotFound {
+		return "", ErrUnauthorized
+	}
+	if err != nil {
+		return "", fmt.Errorf("SessionsRepo.Take: %w", err)
+	}
+
+	return s.issue(ctx, db.SessionWeb, SessionTTL)
+}
+
+// Authenticate checks a web session secret
+func (s *Sessions) Authenticate(ctx context.Context, sessionSecret string) error {
+	if sessionSecret == "" {
+		return ErrUnauthorized
+	}
+
+	_, err := s.repo.Get(ctx, hashSecret(sessionSecret), db.SessionWeb)
+	if err == db.ErrNotFound {
+		return ErrUnauthorized
+	}
+	if err != nil {
+		return fmt.Errorf("SessionsRepo.Get: %w", err)
+	}
+
+	return nil
+}
+
+// Logout ends a web session
+func (s *Sessions) Logout(ctx context.Context, sessionSecret string) error {
+	if err := s.repo.Delete(ctx, hashSecret(sessionSecret)); err != nil {
+		return fmt.Errorf("SessionsRepo.Delete: %w", err)
+	}
+
+	return nil
+}
+
+func (s *Sessions) issue(ctx context.Context, kind string, ttl time.Duration) (string, error) {
+	secret, err := newSecret()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	session := db.Session{
+		ID:        hashSecret(secret),
+		Kind:      kind,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+	if err := s.repo.Add(ctx, session); err != nil {
+		return "", fmt.Errorf("SessionsRepo.Add: %w", err)
+	}
+
+	return secret, nil
+}
@@ -0,0 +1,80 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/unkeep/alfabooker/db"
+)
+
+// Alerts
+const (
+	// AlertOverspending is raised when the total balance falls below the estimated balance
+	AlertOverspending = "overspending"
+)
+
+// CheckExpiry emits EventBudgetExpired once the budget period is over, it is called periodically
+func (d *Domain) CheckExpiry(ctx context.Context) error {
+	b, err := d.budgetRepo.Get(ctx)
+	if err == db.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+
+	if b.ExpiresAt == 0 || b.ExpiresAt > time.Now().Unix() || b.ExpiredNotifiedAt == b.ExpiresAt {
+		return nil
+	}
+
+	b.ExpiredNotifiedAt = b.ExpiresAt
+	if err := d.budgetRepo.Save(ctx, b); err != nil {
+		return fmt.Errorf("BudgetRepo.Save: %w", err)
+	}
+
+	d.emit(ctx, Event{Type: EventBudgetExpired})
+
+	return nil
+}
+
+// checkAlerts raises an alert when the budget gets into an alerting state, the alert is raised again
+// only after the state is left
+func (d *Domain) checkAlerts(ctx context.Context) error {
+	b, err := d.budgetRepo.Get(ctx)
+	if err == db.ErrNotFound {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+	if b.ExpiresAt <= b.StartedAt {
+		return nil
+	}
+
+	overspending := overspent(b, time.Now().Unix())
+	if overspending == b.Overspending {
+		return nil
+	}
+
+	b.Overspending = overspending
+	if err := d.budgetRepo.Save(ctx, b); err != nil {
+		return fmt.Errorf("BudgetRepo.Save: %w", err)
+	}
+
+	if overspending {
+		d.emit(ctx, Event{Type: EventAlertRaised, Alert: AlertOverspending})
+	}
+
+	return nil
+}
+
+func overspent(b db.Budget, now int64) bool {
+	estimated := b.Amount - b.Amount*float64(now-b.StartedAt)/float64(b.ExpiresAt-b.StartedAt)
+	return b.Balance+b.CashBalance-b.ReservedValue < estimated
+}
+
+func (d *Domain) emitTransaction(ctx context.Context, t db.Transaction) {
+	tx := transactionFromDB(t)
+	d.emit(ctx, Event{Type: EventTransactionAdded, Source: t.Source, Transaction: &tx})
+}
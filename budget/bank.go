@@ -0,0 +1,88 @@
+package budget
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// smsFormat is a notification format of a bank
+type smsFormat struct {
+	name            string
+	balanceRE       *regexp.Regexp
+	amountRE        *regexp.Regexp
+	timestampRE     *regexp.Regexp
+	timestampFormat string
+}
+
+// smsFormats are formats of supported banks, a message is tried with each of them
+var smsFormats = []smsFormat{
+	{
+		name:            "gel",
+		balanceRE:       regexp.MustCompile(`Balance:\s([0-9]*\.?[0-9]*)\sGEL`),
+		amountRE:        smsAmountRE,
+		timestampRE:     smsTimestampRE,
+		timestampFormat: smsTimestampFormat,
+	},
+}
+
+func (f smsFormat) parse(sms string) (parsedSMS, error) {
+	var p parsedSMS
+
+	balance, err := f.parseBalance(sms)
+	if err != nil {
+		return p, fmt.Errorf("parseBalance: %w", err)
+	}
+	p.balance = balance
+
+	p.at, p.hasTime = f.parseTimestamp(sms)
+
+	lines := strings.Split(strings.TrimSpace(sms), "\n")
+	if m := f.amountRE.FindStringSubmatch(strings.TrimSpace(lines[0])); m != nil {
+		amount, err := strconv.ParseFloat(m[1], 64)
+		if err == nil {
+			// spending is not signed in SMS
+			if !strings.HasPrefix(m[1], "+") && amount > 0 {
+				amount = -amount
+			}
+			p.amount = amount
+			p.currency = m[2]
+			p.hasAmount = true
+		}
+	}
+
+	// the merchant precedes the timestamp on the same line
+	for _, line := range lines {
+		if loc := f.timestampRE.FindStringIndex(line); loc != nil {
+			p.merchant = strings.TrimSpace(line[:loc[0]])
+			break
+		}
+	}
+
+	return p, nil
+}
+
+func (f smsFormat) parseBalance(sms string) (float64, error) {
+	res := f.balanceRE.FindStringSubmatch(sms)
+	if len(res) != 2 {
+		return 0, fmt.Errorf("unable to parse balance")
+	}
+
+	return strconv.ParseFloat(res[1], 64)
+}
+
+func (f smsFormat) parseTimestamp(sms string) (time.Time, bool) {
+	match := f.timestampRE.FindString(sms)
+	if match == "" {
+		return time.Time{}, false
+	}
+
+	t, err := time.Parse(f.timestampFormat, match)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return t, true
+}
@@ -5,25 +5,31 @@ import (
 	"fmt"
 	"log"
 	"regexp"
-	"strconv"
 	"time"
 
 	"github.com/unkeep/alfabooker/db"
 )
 
 type Domain struct {
-	budgetRepo *db.BudgetRepo
-	balanceRE  *regexp.Regexp
+	budgetRepo  *db.BudgetRepo
+	historyRepo *db.BalanceHistoryRepo
+	periodsRepo *db.PeriodsRepo
+	txRepo      *db.TransactionsRepo
+	smsFormats  []smsFormat
+	listeners   listeners
 }
 
-var smsTimestampRE = regexp.MustCompile(`[0-3][0-9]\/[0-1][0-2]\/202[3-4] [0-2][0-9]:[0-6][0-9]:[0-6][0-9]`)
+var smsTimestampRE = regexp.MustCompile(`[0-3][0-9]\/[0-1][0-9]\/20[0-9]{2} [0-2][0-9]:[0-5][0-9]:[0-5][0-9]`)
 
 var smsTimestampFormat = "02/01/2006 15:04:05"
 
-func NewDomain(repo *db.BudgetRepo) *Domain {
+func NewDomain(repo *db.Repo) *Domain {
 	return &Domain{
-		budgetRepo: repo,
-		balanceRE:  regexp.MustCompile(`Balance:\s([0-9]*\.?[0-9]*)\sGEL`),
+		budgetRepo:  repo.Budget,
+		historyRepo: repo.BalanceHistory,
+		periodsRepo: repo.Periods,
+		txRepo:      repo.Transactions,
+		smsFormats:  smsFormats,
 	}
 }
 
@@ -33,12 +39,17 @@ func (d *Domain) GetStat(ctx context.Context) (*Statistics, error) {
 		return nil, fmt.Errorf("BudgetRepo.Get: %w", err)
 	}
 
-	now := time.Now()
+	return statistics(b, time.Now()), nil
+}
+
+// statistics calculates the statistics of the budget at now
+func statistics(b db.Budget, now time.Time) *Statistics {
 	elapsed := float64(now.Unix() - b.StartedAt)
 	budgetDuration := float64(b.ExpiresAt - b.StartedAt)
 	daysToExpiration := time.Unix(b.ExpiresAt, 0).Sub(now).Hours() / 24.0
 
-	estimatedSpendingCoeff := b.Amount / budgetDuration
+	// the budget has no duration right after it is configured with equal dates
+	estimatedSpendingCoeff := divide(b.Amount, budgetDuration)
 	estimatedSpending := elapsed * estimatedSpendingCoeff
 	estimatedBalance := b.Amount - estimatedSpending
 
@@ -48,7 +59,17 @@ func (d *Domain) GetStat(ctx context.Context) (*Statistics, error) {
 
 	spent := b.Amount - totalBalance
 	elapsedDays := elapsed / 24.0 / 3600.0
-	dailyAverageSpending := spent / elapsedDays
+	// nothing has elapsed right after the budget is started
+	dailyAverageSpending := divide(spent, elapsedDays)
+
+	// what can be spent till the end of the day staying on the estimated line
+	y, m, day := now.Date()
+	endOfDay := time.Date(y, m, day+1, 0, 0, 0, 0, now.Location()).Unix()
+	if endOfDay > b.ExpiresAt {
+		endOfDay = b.ExpiresAt
+	}
+	estimatedEndOfDayBalance := b.Amount - float64(endOfDay-b.StartedAt)*estimatedSpendingCoeff
+	todayAllowance := totalBalance - estimatedEndOfDayBalance
 
 	return &Statistics{
 		BudgetAmount:           b.Amount,
@@ -63,43 +84,124 @@ func (d *Domain) GetStat(ctx context.Context) (*Statistics, error) {
 		BalanceDeviation:       balanceDeviation,
 		Spent:                  spent,
 		DailyAverageSpending:   dailyAverageSpending,
+		TodayAllowance:         todayAllowance,
+	}
+}
+
+// divide returns a/b, or 0 if b is not positive, so statistics stay encodable to JSON
+func divide(a, b float64) float64 {
+	if b <= 0 {
+		return 0
+	}
+	return a / b
+}
+
+// GetBudget returns the current budget configuration and balances
+func (d *Domain) GetBudget(ctx context.Context) (Budget, error) {
+	b, err := d.budgetRepo.Get(ctx)
+	if err != nil {
+		return Budget{}, fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+
+	return Budget{
+		Amount:        b.Amount,
+		StartedAt:     b.StartedAt,
+		ExpiresAt:     b.ExpiresAt,
+		Balance:       b.Balance,
+		BalanceAt:     b.BalanceAt,
+		CashBalance:   b.CashBalance,
+		ReservedValue: b.ReservedValue,
 	}, nil
 }
 
-func (d *Domain) UpdateAccountBalanceFromSMS(ctx context.Context, sms string) error {
-	log.Println("got sms", sms)
+// ConfigureBudget sets the budget amount and period without archiving the current period
+func (d *Domain) ConfigureBudget(ctx context.Context, amount float64, startedAt, expiresAt int64) error {
+	if expiresAt <= startedAt {
+		return fmt.Errorf("budget expires before it starts")
+	}
+
+	b, err := d.budgetRepo.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+
+	b.Amount = amount
+	b.StartedAt = startedAt
+	b.ExpiresAt = expiresAt
+
+	if err := d.budgetRepo.Save(ctx, b); err != nil {
+		return fmt.Errorf("BudgetRepo.Save: %w", err)
+	}
+
+	d.notify(ctx, EventBudgetChanged, "")
+
+	return nil
+}
+
+// ApplySMS records the bank SMS to the ledger and updates the account balance unless the SMS is outdated,
+// the result status is SMSParsed, SMSDuplicate or SMSOutdated
+func (d *Domain) ApplySMS(ctx context.Context, sms SMS) (SMSImportEntry, error) {
+	log.Println("got sms from", sms.Sender, sms.Text)
 
-	balance, err := d.parseBalanceFromSMS(sms)
+	var res SMSImportEntry
+	parsed, err := d.parseSMS(sms)
 	if err != nil {
-		return fmt.Errorf("parseBalanceFromSMS: %w", err)
+		smsTotal.Inc(smsRejected)
+		return res, fmt.Errorf("parseSMS: %w", err)
 	}
+	balance := parsed.balance
 	log.Println("  with balance", balance)
 
-	timeInSMS, hasTimeInSms := d.parseSMSTimestamp(sms)
+	timeInSMS, hasTimeInSms := parsed.at, parsed.hasTime
 	log.Println("  with/without timestamp", hasTimeInSms, timeInSMS.String())
 
+	// the device receive time is a fallback for SMS without a timestamp
+	at, hasTime := sms.ReceivedAt, sms.ReceivedAt != 0
+	if hasTimeInSms {
+		at, hasTime = timeInSMS.Unix(), true
+	}
+	if !hasTime {
+		at = time.Now().Unix()
+	}
+	tx, duplicate, err := d.addSMSTransaction(ctx, sms.Text, parsed, at)
+	if err != nil {
+		return res, fmt.Errorf("addSMSTransaction: %w", err)
+	}
+	res = SMSImportEntry{Status: SMSParsed, At: at, Amount: tx.Amount, Balance: balance}
+	if duplicate {
+		log.Println("ignored duplicate SMS")
+		smsTotal.Inc(smsDuplicate)
+		res.Status = SMSDuplicate
+		return res, nil
+	}
+
 	b, err := d.budgetRepo.Get(ctx)
 	if err != nil {
-		return fmt.Errorf("BudgetRepo.Get: %w", err)
+		return res, fmt.Errorf("BudgetRepo.Get: %w", err)
 	}
 
-	if hasTimeInSms && timeInSMS.Unix() < b.BalanceAt {
+	if hasTime && at < b.BalanceAt {
 		log.Println("ignored outdated balance SMS")
-		return nil
+		smsTotal.Inc(smsOutdated)
+		res.Status = SMSOutdated
+		return res, nil
 	}
 
 	b.Balance = balance
-	if hasTimeInSms {
-		b.BalanceAt = timeInSMS.Unix()
-	} else {
-		b.BalanceAt = time.Now().Unix()
-	}
+	b.BalanceAt = at
 
 	if err := d.budgetRepo.Save(ctx, b); err != nil {
-		return fmt.Errorf("BudgetRepo.Save: %w", err)
+		return res, fmt.Errorf("BudgetRepo.Save: %w", err)
 	}
 
-	return nil
+	if err := d.recordBalance(ctx, b, SourceSMS); err != nil {
+		return res, fmt.Errorf("recordBalance: %w", err)
+	}
+
+	smsTotal.Inc(smsApplied)
+	d.notify(ctx, EventBalanceUpdated, SourceSMS)
+
+	return res, nil
 }
 
 func (d *Domain) UpdateAccountBalance(ctx context.Context, accountBalance float64) error {
@@ -115,6 +217,118 @@ func (d *Domain) UpdateAccountBalance(ctx context.Context, accountBalance float6
 		return fmt.Errorf("BudgetRepo.Save: %w", err)
 	}
 
+	if err := d.recordBalance(ctx, b, SourceCard); err != nil {
+		return fmt.Errorf("recordBalance: %w", err)
+	}
+
+	d.notify(ctx, EventBalanceUpdated, SourceCard)
+
+	return nil
+}
+
+func (d *Domain) SetCash(ctx context.Context, val float64) error {
+	b, err := d.budgetRepo.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+
+	b.CashBalance = val
+
+	if err := d.budgetRepo.Save(ctx, b); err != nil {
+		return fmt.Errorf("BudgetRepo.Save: %w", err)
+	}
+
+	if err := d.recordBalance(ctx, b, SourceCash); err != nil {
+		return fmt.Errorf("recordBalance: %w", err)
+	}
+
+	d.notify(ctx, EventCashUpdated, SourceCash)
+
+	return nil
+}
+
+func (d *Domain) AddCash(ctx context.Context, val float64) error {
+	b, err := d.budgetRepo.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+
+	return d.SetCash(ctx, b.CashBalance+val)
+}
+
+// StartBudget archives the current period and starts a new one for the given number of days
+// with the whole available balance as the budget amount
+func (d *Domain) StartBudget(ctx context.Context, days int) error {
+	return d.startBudget(ctx, days, func(b *db.Budget) {
+		b.Amount = b.Balance + b.CashBalance - b.ReservedValue
+	})
+}
+
+// StartCustomBudget archives the current period and starts a new one for the given number of days
+// with the given amount and reserved value
+func (d *Domain) StartCustomBudget(ctx context.Context, days int, amount float64, reserved float64) error {
+	return d.startBudget(ctx, days, func(b *db.Budget) {
+		b.Amount = amount
+		b.ReservedValue = reserved
+	})
+}
+
+func (d *Domain) startBudget(ctx context.Context, days int, setup func(b *db.Budget)) error {
+	if days <= 0 {
+		return fmt.Errorf("invalid budget duration: %d days", days)
+	}
+
+	b, err := d.budgetRepo.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+
+	if b.StartedAt != 0 {
+		period := db.Period{
+			Amount:    b.Amount,
+			StartedAt: b.StartedAt,
+			ExpiresAt: b.ExpiresAt,
+		}
+		if err := d.periodsRepo.Add(ctx, period); err != nil {
+			return fmt.Errorf("PeriodsRepo.Add: %w", err)
+		}
+	}
+
+	now := time.Now()
+	setup(&b)
+	b.Overspending = false
+	b.StartedAt = now.Unix()
+	b.ExpiresAt = now.Add(time.Hour * time.Duration(24*days)).Unix()
+
+	if err := d.budgetRepo.Save(ctx, b); err != nil {
+		return fmt.Errorf("BudgetRepo.Save: %w", err)
+	}
+
+	if err := d.recordBalance(ctx, b, SourceStart); err != nil {
+		return fmt.Errorf("recordBalance: %w", err)
+	}
+
+	d.notify(ctx, EventBudgetStarted, "")
+
+	return nil
+}
+
+// AddBudget increases (or decreases for negative values) the budget amount
+func (d *Domain) AddBudget(ctx context.Context, val float64) error {
+	b, err := d.budgetRepo.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+
+	b.Amount += val
+	b.StartedAt = time.Now().Unix()
+
+	if err := d.budgetRepo.Save(ctx, b); err != nil {
+		return fmt.Errorf("BudgetRepo.Save: %w", err)
+	}
+
+	d.notify(ctx, EventBudgetChanged, "")
+
 	return nil
 }
 
@@ -124,6 +338,11 @@ func (d *Domain) DecreaseAndAlignBudget(ctx context.Context, byValue float64) er
 		return fmt.Errorf("BudgetRepo.Get: %w", err)
 	}
 
+	// the budget would expire at or before its start
+	if b.Amount <= 0 || byValue <= 0 || byValue >= b.Amount {
+		return fmt.Errorf("invalid align value %v of the budget %v", byValue, b.Amount)
+	}
+
 	decreaseCoeff := byValue / b.Amount
 
 	b.Amount = b.Amount - byValue
@@ -136,6 +355,8 @@ func (d *Domain) DecreaseAndAlignBudget(ctx context.Context, byValue float64) er
 		return fmt.Errorf("budgetRepo.Save: %w", err)
 	}
 
+	d.notify(ctx, EventBudgetAligned, "")
+
 	return nil
 }
 
@@ -151,28 +372,7 @@ func (d *Domain) SetReservedValue(ctx context.Context, val float64) error {
 		return fmt.Errorf("budgetRepo.Save: %w", err)
 	}
 
-	return nil
-}
-
-func (d *Domain) parseBalanceFromSMS(sms string) (float64, error) {
-	res := d.balanceRE.FindSubmatch([]byte(sms))
-	if len(res) != 2 {
-		return 0, fmt.Errorf("unable to parse balance")
-	}
+	d.notify(ctx, EventReserveChanged, "")
 
-	return strconv.ParseFloat(string(res[1]), 64)
-}
-
-func (d *Domain) parseSMSTimestamp(sms string) (time.Time, bool) {
-	match := smsTimestampRE.FindString(sms)
-	if match == "" {
-		return time.Time{}, false
-	}
-
-	t, err := time.Parse(smsTimestampFormat, match)
-	if err != nil {
-		return time.Time{}, false
-	}
-
-	return t, true
+	return nil
 }
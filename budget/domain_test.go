@@ -1,8 +1,11 @@
 package budget
 
 import (
+	"math"
 	"testing"
 	"time"
+
+	"github.com/unkeep/alfabooker/db"
 )
 
 func TestSMSTimestampRE(t *testing.T) {
@@ -39,3 +42,114 @@ Balance: 1072.80 GEL`
 		}
 	})
 }
+
+func TestParseSMS(t *testing.T) {
+	d := NewDomain(&db.Repo{})
+
+	p, err := d.parseSMS(SMS{Text: `12.50 GEL
+MC WORLD ELITE (***3122)
+LTD MP DEVELOPMENT 03/05/2026 09:15:00
+Balance: 1060.30 GEL`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !p.hasAmount || p.amount != -12.5 || p.currency != "GEL" {
+		t.Errorf("unexpected amount %v %s", p.amount, p.currency)
+	}
+	if p.balance != 1060.3 {
+		t.Errorf("unexpected balance %v", p.balance)
+	}
+	if !p.hasTime || p.at.Unix() != 1777799700 {
+		t.Errorf("unexpected time %v", p.at)
+	}
+	if p.merchant != "LTD MP DEVELOPMENT" {
+		t.Errorf("unexpected merchant %q", p.merchant)
+	}
+
+	p, err = d.parseSMS(SMS{Text: "+100 GEL\nBalance: 1160.30 GEL"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.amount != 100 || p.hasTime || p.merchant != "" {
+		t.Errorf("unexpected incoming transfer %+v", p)
+	}
+
+	if _, err := d.parseSMS(SMS{Text: "Your code is 1234"}); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestStatisticsFinite(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	for _, b := range []db.Budget{
+		{Amount: 1000, StartedAt: now.Unix(), ExpiresAt: now.Unix() + 86400*30, Balance: 1000},
+		{Amount: 1000, StartedAt: now.Unix(), ExpiresAt: now.Unix(), Balance: 900},
+	} {
+		stat := statistics(b, now)
+		for _, v := range []float64{stat.EstimatedBalance, stat.BalanceDeviation, stat.DailyAverageSpending, stat.TodayAllowance} {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				t.Errorf("not finite statistics %+v of %+v", stat, b)
+			}
+		}
+	}
+}
+
+func TestFindMatch(t *testing.T) {
+	txs := []Transaction{
+		{ID: "a", At: 1700519450, Amount: -1, Merchant: "LTD MP DEVELOPMENT"},
+		{ID: "b", At: 1700519500, Amount: -1, Merchant: "GOODWILL"},
+		{ID: "c", At: 1700600000, Amount: -25, Merchant: "WOLT.COM"},
+	}
+	matched := make([]bool, len(txs))
+
+	// the same amount at another merchant is matched only if nothing better is left
+	row := StatementRow{At: 1700438400, Amount: -1, Merchant: "GOODWILL TBILISI"}
+	if i := findMatch(row, txs, matched, true); i != 1 {
+		t.Errorf("matched %d", i)
+	}
+	matched[1] = true
+	if i := findMatch(row, txs, matched, true); i != 0 {
+		t.Errorf("matched %d", i)
+	}
+
+	row = StatementRow{At: 1700611200, Amount: -27.5, Merchant: "Wolt.com Tbilisi"}
+	if i := findMatch(row, txs, matched, true); i != -1 {
+		t.Errorf("matched %d", i)
+	}
+	if i := findMatch(row, txs, matched, false); i != 2 {
+		t.Errorf("mismatch %d", i)
+	}
+}
+
+func TestStatementTransactions(t *testing.T) {
+	coffee := StatementRow{At: 1700438400, Amount: -5, Currency: "GEL", Merchant: "COFFEE LAB"}
+	rows := []StatementRow{coffee, coffee, {ID: "ref-1", At: 1700438400, Amount: -5, Currency: "GEL"}}
+
+	txs := statementTransactions(rows)
+	if len(txs) != 3 || txs[0].ID == txs[1].ID {
+		t.Fatalf("identical rows collide: %+v", txs)
+	}
+	if txs[2].ID != SourceStatement+":ref-1" {
+		t.Errorf("unexpected ID of a row with a bank ID %q", txs[2].ID)
+	}
+
+	// a re-imported statement gets the same IDs, so its rows are recognised as duplicates
+	again := statementTransactions(rows[:2])
+	if again[0].ID != txs[0].ID || again[1].ID != txs[1].ID {
+		t.Errorf("IDs are not stable: %+v", again)
+	}
+}
+
+func TestOverspent(t *testing.T) {
+	b := db.Budget{Amount: 1000, StartedAt: 0, ExpiresAt: 1000, Balance: 400, CashBalance: 200, ReservedValue: 100}
+
+	// 500 total vs 500 estimated
+	if overspent(b, 500) {
+		t.Error("on the estimated line")
+	}
+	if !overspent(b, 400) {
+		t.Error("below the estimated line")
+	}
+}
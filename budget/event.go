@@ -0,0 +1,79 @@
+package budget
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+)
+
+// EventType is a kind of a budget change
+type EventType string
+
+// Budget change event types
+const (
+	EventBalanceUpdated EventType = "balance_updated"
+	EventCashUpdated    EventType = "cash_updated"
+	EventReserveChanged EventType = "reserve_changed"
+	EventBudgetStarted  EventType = "budget_started"
+	EventBudgetChanged  EventType = "budget_changed"
+	EventBudgetAligned  EventType = "budget_aligned"
+	// EventBudgetExpired is emitted once the budget period is over
+	EventBudgetExpired EventType = "budget_expired"
+	// EventTransactionAdded is emitted for every new transaction in the ledger
+	EventTransactionAdded EventType = "transaction_added"
+	// EventAlertRaised is emitted when the budget gets into an alerting state
+	EventAlertRaised EventType = "alert_raised"
+)
+
+// EventTypes are all event types
+var EventTypes = []EventType{
+	EventBalanceUpdated, EventCashUpdated, EventReserveChanged, EventBudgetStarted, EventBudgetChanged,
+	EventBudgetAligned, EventBudgetExpired, EventTransactionAdded, EventAlertRaised, EventStatementReconciled,
+}
+
+// Event describes a budget change
+type Event struct {
+	Type EventType `json:"type"`
+	// Source is a balance observation source for balance events
+	Source string    `json:"source,omitempty"`
+	At     time.Time `json:"at"`
+	// Reconciliation is a result of a statement reconciliation for EventStatementReconciled
+	Reconciliation *Reconciliation `json:"reconciliation,omitempty"`
+	// Transaction is the added transaction for EventTransactionAdded
+	Transaction *Transaction `json:"transaction,omitempty"`
+	// Alert is the raised alert for EventAlertRaised
+	Alert string `json:"alert,omitempty"`
+}
+
+type listeners struct {
+	mu sync.RWMutex
+	hs []func(ctx context.Context, e Event)
+}
+
+// Subscribe registers a handler called synchronously after every budget change
+func (d *Domain) Subscribe(h func(ctx context.Context, e Event)) {
+	d.listeners.mu.Lock()
+	defer d.listeners.mu.Unlock()
+
+	d.listeners.hs = append(d.listeners.hs, h)
+}
+
+func (d *Domain) notify(ctx context.Context, t EventType, source string) {
+	d.emit(ctx, Event{Type: t, Source: source})
+
+	if err := d.checkAlerts(ctx); err != nil {
+		log.Println("checkAlerts:", err)
+	}
+}
+
+func (d *Domain) emit(ctx context.Context, e Event) {
+	d.listeners.mu.RLock()
+	hs := d.listeners.hs
+	d.listeners.mu.RUnlock()
+
+	e.At = time.Now()
+	for _, h := range hs {
+		h(ctx, e)
+	}
+}
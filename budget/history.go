@@ -0,0 +1,108 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/unkeep/alfabooker/db"
+)
+
+// Balance observation sources
+const (
+	SourceSMS   = "sms"
+	SourceCard  = "card"
+	SourceCash  = "cash"
+	SourceStart = "start"
+)
+
+// BalancePoint is a total balance observed at some moment
+type BalancePoint struct {
+	At    int64   `json:"at"`
+	Total float64 `json:"total"`
+}
+
+// Period is a budget period together with the balance observed during it
+type Period struct {
+	Amount    float64        `json:"amount"`
+	StartedAt int64          `json:"started_at"`
+	ExpiresAt int64          `json:"expires_at"`
+	Points    []BalancePoint `json:"points"`
+}
+
+// EstimatedBalance returns the linearly estimated balance at the given moment
+func (p Period) EstimatedBalance(at int64) float64 {
+	duration := float64(p.ExpiresAt - p.StartedAt)
+	if duration <= 0 {
+		return p.Amount
+	}
+
+	return p.Amount - p.Amount*float64(at-p.StartedAt)/duration
+}
+
+// GetPeriods returns the current period followed by up to pastCount previous ones
+func (d *Domain) GetPeriods(ctx context.Context, pastCount int) ([]Period, error) {
+	b, err := d.budgetRepo.Get(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+
+	periods := []Period{{
+		Amount:    b.Amount,
+		StartedAt: b.StartedAt,
+		ExpiresAt: b.ExpiresAt,
+	}}
+
+	if pastCount > 0 {
+		past, err := d.periodsRepo.GetLast(ctx, pastCount)
+		if err != nil {
+			return nil, fmt.Errorf("PeriodsRepo.GetLast: %w", err)
+		}
+		for _, p := range past {
+			periods = append(periods, Period{
+				Amount:    p.Amount,
+				StartedAt: p.StartedAt,
+				ExpiresAt: p.ExpiresAt,
+			})
+		}
+	}
+
+	for i := range periods {
+		to := periods[i].ExpiresAt
+		if i == 0 && time.Now().Unix() < to {
+			to = time.Now().Unix()
+		}
+		points, err := d.historyRepo.GetRange(ctx, periods[i].StartedAt, to)
+		if err != nil {
+			return nil, fmt.Errorf("BalanceHistoryRepo.GetRange: %w", err)
+		}
+		for _, p := range points {
+			periods[i].Points = append(periods[i].Points, BalancePoint{
+				At:    p.At,
+				Total: p.Balance + p.CashBalance - p.ReservedValue,
+			})
+		}
+	}
+
+	return periods, nil
+}
+
+func (d *Domain) recordBalance(ctx context.Context, b db.Budget, source string) error {
+	at := b.BalanceAt
+	if source != SourceSMS || at == 0 {
+		at = time.Now().Unix()
+	}
+
+	p := db.BalancePoint{
+		At:            at,
+		Source:        source,
+		Balance:       b.Balance,
+		CashBalance:   b.CashBalance,
+		ReservedValue: b.ReservedValue,
+	}
+	if err := d.historyRepo.Add(ctx, p); err != nil {
+		return fmt.Errorf("BalanceHistoryRepo.Add: %w", err)
+	}
+
+	return nil
+}
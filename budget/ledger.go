@@ -0,0 +1,65 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/unkeep/alfabooker/db"
+)
+
+// Transaction is an account operation, negative amounts are spending
+type Transaction struct {
+	ID       string  `json:"id"`
+	At       int64   `json:"at"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency,omitempty"`
+	Merchant string  `json:"merchant,omitempty"`
+	// Balance is the account balance after the transaction if known
+	Balance *float64 `json:"balance,omitempty"`
+	Source  string   `json:"source"`
+}
+
+// GetTransactions returns transactions made within [from, to] sorted by time,
+// from defaults to the current budget start and to defaults to now
+func (d *Domain) GetTransactions(ctx context.Context, from, to int64) ([]Transaction, error) {
+	if from == 0 {
+		b, err := d.budgetRepo.Get(ctx)
+		if err != nil && err != db.ErrNotFound {
+			return nil, fmt.Errorf("BudgetRepo.Get: %w", err)
+		}
+		from = b.StartedAt
+	}
+	if to == 0 {
+		to = time.Now().Unix()
+	}
+
+	txs, err := d.txRepo.GetRange(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("TransactionsRepo.GetRange: %w", err)
+	}
+
+	res := make([]Transaction, 0, len(txs))
+	for _, t := range txs {
+		res = append(res, transactionFromDB(t))
+	}
+
+	return res, nil
+}
+
+func transactionFromDB(t db.Transaction) Transaction {
+	tx := Transaction{
+		ID:       t.ID,
+		At:       t.At,
+		Amount:   t.Amount,
+		Currency: t.Currency,
+		Merchant: t.Merchant,
+		Source:   t.Source,
+	}
+	if t.HasBalance {
+		balance := t.Balance
+		tx.Balance = &balance
+	}
+
+	return tx
+}
@@ -0,0 +1,13 @@
+package budget
+
+import "github.com/unkeep/alfabooker/metrics"
+
+// SMS processing results
+const (
+	smsApplied   = "applied"
+	smsOutdated  = "outdated"
+	smsDuplicate = "duplicate"
+	smsRejected  = "rejected"
+)
+
+var smsTotal = metrics.NewCounter("alfabooker_sms_total", "Bank SMS received by the processing result: applied, outdated (older than the current balance), duplicate or rejected (not parsed).", "result")
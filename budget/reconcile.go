@@ -0,0 +1,231 @@
+package budget
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"unicode"
+
+	"github.com/unkeep/alfabooker/db"
+)
+
+// SourceStatement marks transactions added from a bank statement
+const SourceStatement = "statement"
+
+// EventStatementReconciled is emitted after a bank statement is reconciled
+const EventStatementReconciled EventType = "statement_reconciled"
+
+// matchWindow is how far a statement row date may be from the recorded transaction,
+// banks post card operations days after they are made
+const matchWindow = 3 * 24 * 3600
+
+// amountTolerance is a difference of amounts considered equal
+const amountTolerance = 0.005
+
+// StatementRow is an operation listed in a bank statement
+type StatementRow struct {
+	// ID is a bank reference of the operation if the statement has one
+	ID       string  `json:"id,omitempty"`
+	At       int64   `json:"at"`
+	Amount   float64 `json:"amount"`
+	Currency string  `json:"currency,omitempty"`
+	Merchant string  `json:"merchant,omitempty"`
+}
+
+// Mismatch is a statement row resembling a recorded transaction with a different amount
+type Mismatch struct {
+	Row         StatementRow `json:"row"`
+	Transaction Transaction  `json:"transaction"`
+}
+
+// Reconciliation is a result of a statement reconciliation
+type Reconciliation struct {
+	Rows    int `json:"rows"`
+	Matched int `json:"matched"`
+	// Added are rows missing in the ledger, they are added to it
+	Added []StatementRow `json:"added"`
+	// Mismatches are rows having a recorded transaction of the same day and merchant but another amount,
+	// they are only reported, since they are the recorded operations
+	Mismatches []Mismatch `json:"mismatches"`
+	// Unmatched are recorded transactions within the statement range missing in the statement
+	Unmatched []Transaction `json:"unmatched"`
+}
+
+// ReconcileStatement matches statement rows against the ledger by amount, date and merchant
+// and adds missing rows to it
+func (d *Domain) ReconcileStatement(ctx context.Context, rows []StatementRow) (Reconciliation, error) {
+	rec := Reconciliation{Rows: len(rows), Added: []StatementRow{}, Mismatches: []Mismatch{}, Unmatched: []Transaction{}}
+	if len(rows) == 0 {
+		return rec, nil
+	}
+
+	from, to := rows[0].At, rows[0].At
+	for _, r := range rows {
+		from = min64(from, r.At)
+		to = max64(to, r.At)
+	}
+
+	txs, err := d.GetTransactions(ctx, from-matchWindow, to+matchWindow)
+	if err != nil {
+		return rec, fmt.Errorf("GetTransactions: %w", err)
+	}
+	matched := make([]bool, len(txs))
+	rowTxs := statementTransactions(rows)
+
+	// alerts and webhooks are about the current budget, not about months old operations
+	var periodFrom, periodTo int64
+	b, err := d.budgetRepo.Get(ctx)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		return rec, fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+	if err == nil {
+		periodFrom, periodTo = b.StartedAt, b.ExpiresAt
+	}
+
+	for k, r := range rows {
+		if i := findMatch(r, txs, matched, true); i >= 0 {
+			matched[i] = true
+			rec.Matched++
+			continue
+		}
+
+		if i := findMatch(r, txs, matched, false); i >= 0 {
+			matched[i] = true
+			rec.Mismatches = append(rec.Mismatches, Mismatch{Row: r, Transaction: txs[i]})
+			continue
+		}
+		rec.Added = append(rec.Added, r)
+
+		tx := rowTxs[k]
+		duplicate, err := d.txRepo.Add(ctx, tx)
+		if err != nil {
+			return rec, fmt.Errorf("TransactionsRepo.Add: %w", err)
+		}
+		if !duplicate && tx.At >= periodFrom && tx.At < periodTo {
+			d.emitTransaction(ctx, tx)
+		}
+	}
+
+	// transactions close to the statement bounds may belong to the neighbouring statements
+	for i, tx := range txs {
+		if !matched[i] && tx.At >= from && tx.At <= to+24*3600 && tx.Source != SourceStatement {
+			rec.Unmatched = append(rec.Unmatched, tx)
+		}
+	}
+
+	d.emit(ctx, Event{Type: EventStatementReconciled, Source: SourceStatement, Reconciliation: &rec})
+
+	return rec, nil
+}
+
+// findMatch returns an index of the closest unmatched transaction having the row amount,
+// or if sameAmount is false, made on the row day at the same merchant
+func findMatch(r StatementRow, txs []Transaction, matched []bool, sameAmount bool) int {
+	best, bestScore := -1, 0.0
+	for i, tx := range txs {
+		if matched[i] {
+			continue
+		}
+
+		distance := math.Abs(float64(tx.At - r.At))
+		merchant := sameMerchant(r.Merchant, tx.Merchant)
+		if sameAmount {
+			if distance > matchWindow || math.Abs(tx.Amount-r.Amount) > amountTolerance {
+				continue
+			}
+		} else if distance > 24*3600 || !merchant {
+			continue
+		}
+
+		// the same merchant outweighs any distance within the window
+		score := distance
+		if !merchant {
+			score += 2 * matchWindow
+		}
+		if best < 0 || score < bestScore {
+			best, bestScore = i, score
+		}
+	}
+
+	return best
+}
+
+// sameMerchant reports whether merchant names look alike, banks abbreviate and pad them differently
+func sameMerchant(a, b string) bool {
+	wa, wb := merchantWords(a), merchantWords(b)
+	if len(wa) == 0 || len(wb) == 0 {
+		return false
+	}
+
+	for w := range wa {
+		if wb[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// merchantWords returns significant words of a merchant name
+func merchantWords(s string) map[string]bool {
+	words := map[string]bool{}
+	for _, w := range strings.FieldsFunc(strings.ToUpper(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		// legal forms like LTD or LLC do not identify a merchant
+		if len([]rune(w)) > 3 {
+			words[w] = true
+		}
+	}
+	return words
+}
+
+// statementTransactions converts statement rows to ledger transactions, rows without a bank ID are identified
+// by their contents and the occurrence number, so identical operations of a day are kept apart
+// while re-imported statements are still recognised
+func statementTransactions(rows []StatementRow) []db.Transaction {
+	occurrences := map[string]int{}
+	txs := make([]db.Transaction, 0, len(rows))
+	for _, r := range rows {
+		id := r.ID
+		if id == "" {
+			key := fmt.Sprintf("%d|%.2f|%s|%s", r.At, r.Amount, r.Currency, r.Merchant)
+			n := occurrences[key]
+			occurrences[key]++
+			// the first occurrence keeps the key of ledgers recorded before numbering
+			if n > 0 {
+				key += fmt.Sprintf("|%d", n)
+			}
+			hash := sha256.Sum256([]byte(key))
+			id = hex.EncodeToString(hash[:])
+		}
+
+		txs = append(txs, db.Transaction{
+			ID:       SourceStatement + ":" + id,
+			At:       r.At,
+			Amount:   r.Amount,
+			Currency: r.Currency,
+			Merchant: r.Merchant,
+			Source:   SourceStatement,
+		})
+	}
+
+	return txs
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
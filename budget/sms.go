@@ -0,0 +1,254 @@
+package budget
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/db"
+)
+
+// smsAmountRE matches the first SMS line like "12.50 GEL", a leading "+" marks incoming transfers
+var smsAmountRE = regexp.MustCompile(`^([+-]?[0-9]+(?:\.[0-9]+)?)\s+([A-Z]{3})$`)
+
+// SMS is a bank SMS
+type SMS struct {
+	Text string
+	// Sender is an SMS sender ID or an email address, it is only logged, optional
+	Sender string
+	// ReceivedAt is unix time the SMS was received at, used if the text has no timestamp
+	ReceivedAt int64
+}
+
+// ErrUnrecognizedSMS is returned for texts matching none of the bank SMS formats
+var ErrUnrecognizedSMS = errors.New("unrecognized SMS")
+
+// SMS import statuses
+const (
+	SMSParsed    = "parsed"
+	SMSDuplicate = "duplicate"
+	SMSFailed    = "failed"
+	// SMSOutdated is an SMS recorded to the ledger without changing the balance, since it is older than the balance
+	SMSOutdated = "outdated"
+)
+
+// SMSPreview is a bank SMS recognised in a text
+type SMSPreview struct {
+	// Amount is the transaction amount, negative for spending, known only if HasAmount
+	Amount    float64
+	HasAmount bool
+	Currency  string
+	Merchant  string
+	Balance   float64
+	// At is the SMS timestamp, 0 if the text has none
+	At int64
+}
+
+// SMSImportEntry is an import result of a single SMS
+type SMSImportEntry struct {
+	Index   int     `json:"index"`
+	Status  string  `json:"status"`
+	At      int64   `json:"at,omitempty"`
+	Amount  float64 `json:"amount,omitempty"`
+	Balance float64 `json:"balance,omitempty"`
+	Error   string  `json:"error,omitempty"`
+}
+
+// SMSImportReport is an import result of SMS in their original order
+type SMSImportReport struct {
+	Parsed     int              `json:"parsed"`
+	Duplicates int              `json:"duplicates"`
+	Failed     int              `json:"failed"`
+	Entries    []SMSImportEntry `json:"entries"`
+}
+
+// parsedSMS is data extracted from an SMS text
+type parsedSMS struct {
+	balance   float64
+	at        time.Time
+	hasTime   bool
+	amount    float64
+	currency  string
+	hasAmount bool
+	merchant  string
+}
+
+// PreviewSMS recognises a bank SMS in the text without recording it
+func (d *Domain) PreviewSMS(text string) (SMSPreview, error) {
+	p, err := d.parseSMS(SMS{Text: text})
+	if err != nil {
+		return SMSPreview{}, err
+	}
+
+	preview := SMSPreview{
+		Amount:    p.amount,
+		HasAmount: p.hasAmount,
+		Currency:  p.currency,
+		Merchant:  p.merchant,
+		Balance:   p.balance,
+	}
+	if p.hasTime {
+		preview.At = p.at.Unix()
+	}
+
+	return preview, nil
+}
+
+// LastSMSAt returns the time of the latest recorded bank SMS, 0 if none has been recorded
+func (d *Domain) LastSMSAt(ctx context.Context) (int64, error) {
+	tx, err := d.txRepo.GetLastBySource(ctx, SourceSMS)
+	if err == db.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("TransactionsRepo.GetLastBySource: %w", err)
+	}
+
+	return tx.At, nil
+}
+
+// parseSMS parses the SMS with the first matching bank format
+func (d *Domain) parseSMS(sms SMS) (parsedSMS, error) {
+	err := fmt.Errorf("no SMS formats")
+	for _, f := range d.smsFormats {
+		var p parsedSMS
+		p, err = f.parse(sms.Text)
+		if err == nil {
+			return p, nil
+		}
+	}
+
+	return parsedSMS{}, fmt.Errorf("%w: %v", ErrUnrecognizedSMS, err)
+}
+
+// addSMSTransaction records the SMS to the ledger and reports whether it has been already recorded
+func (d *Domain) addSMSTransaction(ctx context.Context, sms string, p parsedSMS, at int64) (db.Transaction, bool, error) {
+	hash := sha256.Sum256([]byte(strings.TrimSpace(sms)))
+	tx := db.Transaction{
+		ID:         SourceSMS + ":" + hex.EncodeToString(hash[:]),
+		At:         at,
+		Amount:     p.amount,
+		Currency:   p.currency,
+		Merchant:   p.merchant,
+		Balance:    p.balance,
+		HasBalance: true,
+		Source:     SourceSMS,
+		Text:       sms,
+	}
+
+	// SMS without an amount line still change the balance
+	if !p.hasAmount {
+		prev, err := d.txRepo.GetLastWithBalance(ctx, at)
+		if err != nil && err != db.ErrNotFound {
+			return tx, false, fmt.Errorf("TransactionsRepo.GetLastWithBalance: %w", err)
+		}
+		if err == nil {
+			tx.Amount = p.balance - prev.Balance
+		}
+	}
+
+	duplicate, err := d.txRepo.Add(ctx, tx)
+	if err != nil {
+		return tx, false, fmt.Errorf("TransactionsRepo.Add: %w", err)
+	}
+	if !duplicate {
+		d.emitTransaction(ctx, tx)
+	}
+
+	return tx, duplicate, nil
+}
+
+// ImportSMS records historical SMS in timestamp order and updates the account balance if any of them is newer
+func (d *Domain) ImportSMS(ctx context.Context, msgs []SMS) (SMSImportReport, error) {
+	report := SMSImportReport{Entries: make([]SMSImportEntry, len(msgs))}
+
+	type parsedItem struct {
+		idx int
+		p   parsedSMS
+		at  int64
+	}
+	var items []parsedItem
+
+	for i, m := range msgs {
+		entry := &report.Entries[i]
+		entry.Index = i
+
+		p, err := d.parseSMS(m)
+		if err == nil && !p.hasTime && m.ReceivedAt == 0 {
+			err = fmt.Errorf("no timestamp")
+		}
+		if err != nil {
+			entry.Status = SMSFailed
+			entry.Error = err.Error()
+			report.Failed++
+			smsTotal.Inc(smsRejected)
+			continue
+		}
+
+		at := m.ReceivedAt
+		if p.hasTime {
+			at = p.at.Unix()
+		}
+		items = append(items, parsedItem{idx: i, p: p, at: at})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].at < items[j].at
+	})
+
+	b, err := d.budgetRepo.Get(ctx)
+	if err != nil && err != db.ErrNotFound {
+		return report, fmt.Errorf("BudgetRepo.Get: %w", err)
+	}
+	balanceUpdated := false
+
+	for _, item := range items {
+		tx, duplicate, err := d.addSMSTransaction(ctx, msgs[item.idx].Text, item.p, item.at)
+		if err != nil {
+			return report, fmt.Errorf("addSMSTransaction: %w", err)
+		}
+
+		entry := &report.Entries[item.idx]
+		entry.At = item.at
+		entry.Amount = tx.Amount
+		entry.Balance = item.p.balance
+		if duplicate {
+			entry.Status = SMSDuplicate
+			report.Duplicates++
+			smsTotal.Inc(smsDuplicate)
+			continue
+		}
+		entry.Status = SMSParsed
+		report.Parsed++
+
+		point := b
+		point.Balance = item.p.balance
+		point.BalanceAt = item.at
+		if err := d.recordBalance(ctx, point, SourceSMS); err != nil {
+			return report, fmt.Errorf("recordBalance: %w", err)
+		}
+
+		if item.at < b.BalanceAt {
+			smsTotal.Inc(smsOutdated)
+			continue
+		}
+		b = point
+		balanceUpdated = true
+		smsTotal.Inc(smsApplied)
+	}
+
+	if balanceUpdated {
+		if err := d.budgetRepo.Save(ctx, b); err != nil {
+			return report, fmt.Errorf("BudgetRepo.Save: %w", err)
+		}
+		d.notify(ctx, EventBalanceUpdated, SourceSMS)
+	}
+
+	return report, nil
+}
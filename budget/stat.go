@@ -1,5 +1,16 @@
 package budget
 
+// Budget is a budget configuration together with the current balances
+type Budget struct {
+	Amount        float64 `json:"amount"`
+	StartedAt     int64   `json:"started_at"`
+	ExpiresAt     int64   `json:"expires_at"`
+	Balance       float64 `json:"account_balance"`
+	BalanceAt     int64   `json:"account_balance_at"`
+	CashBalance   float64 `json:"cash_balance"`
+	ReservedValue float64 `json:"reserved_balance"`
+}
+
 type Statistics struct {
 	BudgetAmount float64 `json:"budget_amount"`
 
@@ -17,4 +28,5 @@ type Statistics struct {
 
 	Spent                float64 `json:"spent"`
 	DailyAverageSpending float64 `json:"daily_average_spending"`
+	TodayAllowance       float64 `json:"today_allowance"`
 }
@@ -0,0 +1,146 @@
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"math"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+const (
+	width   = 800
+	height  = 480
+	padding = 30
+)
+
+var (
+	bgColor        = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+	gridColor      = color.RGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff}
+	axisColor      = color.RGBA{R: 0x60, G: 0x60, B: 0x60, A: 0xff}
+	estimatedColor = color.RGBA{R: 0x3b, G: 0x82, B: 0xf6, A: 0xff}
+	actualColor    = color.RGBA{R: 0x16, G: 0xa3, B: 0x4a, A: 0xff}
+)
+
+const secondsInDay = 24 * 3600
+
+// ErrEmptyPeriod is returned for a period which does not expire after it is started, e.g. of a never started budget
+var ErrEmptyPeriod = errors.New("empty period")
+
+// RenderBalance renders actual total balance vs the estimated linear balance of the period as PNG
+func RenderBalance(p budget.Period) ([]byte, error) {
+	if p.ExpiresAt <= p.StartedAt {
+		return nil, fmt.Errorf("%w: %d-%d", ErrEmptyPeriod, p.StartedAt, p.ExpiresAt)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: bgColor}, image.Point{}, draw.Src)
+
+	minX, maxX := p.StartedAt, p.ExpiresAt
+	minY, maxY := math.Min(0, p.Amount), math.Max(0, p.Amount)
+	for _, pt := range p.Points {
+		minY = math.Min(minY, pt.Total)
+		maxY = math.Max(maxY, pt.Total)
+		if pt.At > maxX {
+			maxX = pt.At
+		}
+	}
+	if maxY == minY {
+		maxY = minY + 1
+	}
+	margin := (maxY - minY) * 0.05
+	minY, maxY = minY-margin, maxY+margin
+
+	c := canvas{img: img, minX: minX, maxX: maxX, minY: minY, maxY: maxY}
+
+	// a vertical grid line per day unless it is too dense
+	if days := (maxX - minX) / secondsInDay; days <= 62 {
+		for t := minX + secondsInDay; t < maxX; t += secondsInDay {
+			c.line(t, minY, t, maxY, gridColor, 1)
+		}
+	}
+	c.line(minX, 0, maxX, 0, axisColor, 1)
+	c.line(minX, minY, minX, maxY, axisColor, 1)
+
+	c.line(p.StartedAt, p.Amount, p.ExpiresAt, 0, estimatedColor, 2)
+
+	// the balance keeps its value until the next observation
+	for i := 1; i < len(p.Points); i++ {
+		prev, cur := p.Points[i-1], p.Points[i]
+		c.line(prev.At, prev.Total, cur.At, prev.Total, actualColor, 3)
+		c.line(cur.At, prev.Total, cur.At, cur.Total, actualColor, 3)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("png.Encode: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+type canvas struct {
+	img        *image.RGBA
+	minX, maxX int64
+	minY, maxY float64
+}
+
+func (c canvas) point(x int64, y float64) (int, int) {
+	px := padding + int(float64(x-c.minX)/float64(c.maxX-c.minX)*float64(width-2*padding))
+	py := height - padding - int((y-c.minY)/(c.maxY-c.minY)*float64(height-2*padding))
+
+	return px, py
+}
+
+// line draws a line of the given thickness using Bresenham's algorithm
+func (c canvas) line(x0 int64, y0 float64, x1 int64, y1 float64, col color.Color, thickness int) {
+	ax, ay := c.point(x0, y0)
+	bx, by := c.point(x1, y1)
+
+	dx, dy := abs(bx-ax), -abs(by-ay)
+	sx, sy := 1, 1
+	if ax > bx {
+		sx = -1
+	}
+	if ay > by {
+		sy = -1
+	}
+	e := dx + dy
+
+	for {
+		c.dot(ax, ay, col, thickness)
+		if ax == bx && ay == by {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e += dy
+			ax += sx
+		}
+		if e2 <= dx {
+			e += dx
+			ay += sy
+		}
+	}
+}
+
+func (c canvas) dot(x, y int, col color.Color, thickness int) {
+	half := thickness / 2
+	for i := x - half; i <= x-half+thickness-1; i++ {
+		for j := y - half; j <= y-half+thickness-1; j++ {
+			c.img.Set(i, j, col)
+		}
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,53 @@
+package chart
+
+import (
+	"bytes"
+	"errors"
+	"image/png"
+	"testing"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+func TestRenderBalance(t *testing.T) {
+	t.Run("period", func(t *testing.T) {
+		p := budget.Period{
+			Amount:    1000,
+			StartedAt: 1700000000,
+			ExpiresAt: 1700000000 + 10*secondsInDay,
+			Points: []budget.BalancePoint{
+				{At: 1700000000, Total: 1000},
+				{At: 1700000000 + 2*secondsInDay, Total: 700},
+				{At: 1700000000 + 5*secondsInDay, Total: 550},
+			},
+		}
+
+		data, err := RenderBalance(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if img.Bounds().Dx() != width || img.Bounds().Dy() != height {
+			t.Errorf("unexpected size %v", img.Bounds())
+		}
+
+		c := canvas{minX: p.StartedAt, maxX: p.ExpiresAt, minY: -50, maxY: 1050}
+		x, y := c.point(1700000000+secondsInDay, 1000)
+		r, g, b, _ := img.At(x, y).RGBA()
+		ar, ag, ab, _ := actualColor.RGBA()
+		if r != ar || g != ag || b != ab {
+			t.Errorf("expected actual balance line at (%d, %d)", x, y)
+		}
+	})
+
+	t.Run("empty period", func(t *testing.T) {
+		if _, err := RenderBalance(budget.Period{}); !errors.Is(err, ErrEmptyPeriod) {
+			t.Errorf("unexpected error %v", err)
+		}
+	})
+}
@@ -0,0 +1,82 @@
+// Command alfabooker runs the bot as a standalone HTTP server
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kelseyhightower/envconfig"
+
+	"github.com/unkeep/alfabooker/app"
+)
+
+type config struct {
+	Port string `default:"8080"`
+	// ShutdownTimeout limits finishing requests and handling received updates on SIGINT or SIGTERM
+	ShutdownTimeout time.Duration `default:"30s"`
+}
+
+func main() {
+	os.Exit(run())
+}
+
+// run serves HTTP until a signal and returns the exit code
+func run() int {
+	var cfg config
+	if err := envconfig.Process("AB", &cfg); err != nil {
+		log.Println("envconfig.Process:", err)
+		return 1
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	a, err := app.New()
+	if err != nil {
+		log.Println("app.New:", err)
+		return 1
+	}
+
+	srv := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           a.Handler,
+		ReadHeaderTimeout: time.Second * 10,
+	}
+	srv.RegisterOnShutdown(a.CloseStreams)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		log.Println("serving HTTP on", srv.Addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	exitCode := 0
+	select {
+	case <-ctx.Done():
+		log.Println("shutting down")
+	case err := <-serveErr:
+		log.Println("srv.ListenAndServe:", err)
+		exitCode = 1
+	}
+	stop()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(shutdownCtx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Println("srv.Shutdown:", err)
+		exitCode = 1
+	}
+	if err := a.Shutdown(shutdownCtx); err != nil {
+		log.Println("app.Shutdown:", err)
+		exitCode = 1
+	}
+
+	return exitCode
+}
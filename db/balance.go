@@ -0,0 +1,54 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// BalancePoint is a single balance observation
+type BalancePoint struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	At            int64
+	Source        string
+	Balance       float64
+	CashBalance   float64
+	ReservedValue float64
+}
+
+func getBalanceHistoryRepo(mngDB *mongo.Database) *BalanceHistoryRepo {
+	return &BalanceHistoryRepo{c: mngDB.Collection("balance_history")}
+}
+
+// BalanceHistoryRepo provides access to the balance time series
+type BalanceHistoryRepo struct {
+	c *mongo.Collection
+}
+
+// Add appends a point to the time series
+func (r *BalanceHistoryRepo) Add(ctx context.Context, p BalancePoint) error {
+	_, err := r.c.InsertOne(ctx, p)
+
+	return err
+}
+
+// GetRange returns points observed within [from, to] sorted by time
+func (r *BalanceHistoryRepo) GetRange(ctx context.Context, from, to int64) ([]BalancePoint, error) {
+	filter := bson.M{"at": bson.M{"$gte": from, "$lte": to}}
+	opts := options.Find().SetSort(bson.M{"at": 1})
+
+	cur, err := r.c.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var points []BalancePoint
+	if err := cur.All(ctx, &points); err != nil {
+		return nil, err
+	}
+
+	return points, nil
+}
@@ -17,6 +17,10 @@ type Budget struct {
 	CashBalance   float64
 	ReservedValue float64
 	BalanceAt     int64
+	// ExpiredNotifiedAt is ExpiresAt of the budget whose expiration has been notified
+	ExpiredNotifiedAt int64
+	// Overspending is set while the overspending alert is raised
+	Overspending bool
 }
 
 const budgetID = "budget"
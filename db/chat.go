@@ -0,0 +1,70 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Chat is a per chat settings
+type Chat struct {
+	ID   int64 `bson:"_id"`
+	Lang string
+	// StatusEnabled is set when the chat has a live pinned status message
+	StatusEnabled bool
+	StatusMsgID   int
+}
+
+func getChatsRepo(mngDB *mongo.Database) *ChatsRepo {
+	return &ChatsRepo{c: mngDB.Collection("chats")}
+}
+
+// ChatsRepo provides access to chat settings
+type ChatsRepo struct {
+	c *mongo.Collection
+}
+
+// Get gets settings of the given chat
+func (r *ChatsRepo) Get(ctx context.Context, id int64) (Chat, error) {
+	filter := bson.M{"_id": id}
+	res := r.c.FindOne(ctx, filter)
+	var chat Chat
+	if res.Err() != nil {
+		return chat, res.Err()
+	}
+
+	if err := res.Decode(&chat); err != nil {
+		return chat, err
+	}
+
+	return chat, nil
+}
+
+// GetWithStatus gets settings of chats with an enabled status message
+func (r *ChatsRepo) GetWithStatus(ctx context.Context) ([]Chat, error) {
+	cur, err := r.c.Find(ctx, bson.M{"statusenabled": true})
+	if err != nil {
+		return nil, err
+	}
+
+	var chats []Chat
+	if err := cur.All(ctx, &chats); err != nil {
+		return nil, err
+	}
+
+	return chats, nil
+}
+
+// Save saves chat settings
+func (r *ChatsRepo) Save(ctx context.Context, chat Chat) error {
+	filter := bson.M{"_id": chat.ID}
+	upd := bson.M{"$set": chat}
+	upsert := true
+	opts := &options.UpdateOptions{Upsert: &upsert}
+
+	_, err := r.c.UpdateOne(ctx, filter, upd, opts)
+
+	return err
+}
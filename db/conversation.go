@@ -0,0 +1,65 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Conversation is a state of a multi-step dialog with a chat
+type Conversation struct {
+	ChatID int64 `bson:"_id"`
+	Flow   string
+	Step   string
+	Values map[string]float64
+	// Text is a text the flow is about, e.g. a pasted SMS
+	Text      string
+	MsgID     int
+	UpdatedAt int64
+}
+
+func getConversationsRepo(mngDB *mongo.Database) *ConversationsRepo {
+	return &ConversationsRepo{c: mngDB.Collection("conversations")}
+}
+
+// ConversationsRepo provides access to per chat conversation states
+type ConversationsRepo struct {
+	c *mongo.Collection
+}
+
+// Get gets a conversation of the given chat
+func (r *ConversationsRepo) Get(ctx context.Context, chatID int64) (Conversation, error) {
+	filter := bson.M{"_id": chatID}
+	res := r.c.FindOne(ctx, filter)
+	var conv Conversation
+	if res.Err() != nil {
+		return conv, res.Err()
+	}
+
+	if err := res.Decode(&conv); err != nil {
+		return conv, err
+	}
+
+	return conv, nil
+}
+
+// Save saves a conversation
+func (r *ConversationsRepo) Save(ctx context.Context, conv Conversation) error {
+	filter := bson.M{"_id": conv.ChatID}
+	upd := bson.M{"$set": conv}
+	upsert := true
+	opts := &options.UpdateOptions{Upsert: &upsert}
+
+	_, err := r.c.UpdateOne(ctx, filter, upd, opts)
+
+	return err
+}
+
+// Delete deletes a conversation of the given chat
+func (r *ConversationsRepo) Delete(ctx context.Context, chatID int64) error {
+	_, err := r.c.DeleteOne(ctx, bson.M{"_id": chatID})
+
+	return err
+}
@@ -1,8 +1,26 @@
 package db
 
 import (
+	"errors"
+
 	"go.mongodb.org/mongo-driver/mongo"
 )
 
 // ErrNotFound is an alias of mongo.ErrNoDocuments
 var ErrNotFound = mongo.ErrNoDocuments
+
+// duplicateKeyCode is a MongoDB error code of a unique index violation
+const duplicateKeyCode = 11000
+
+func isDuplicateKey(err error) bool {
+	var we mongo.WriteException
+	if errors.As(err, &we) {
+		for _, e := range we.WriteErrors {
+			if e.Code == duplicateKeyCode {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Nonce is a used nonce of a signed request
+type Nonce struct {
+	ID string `bson:"_id"`
+	// ExpiresAt is when the nonce is forgotten, requests with its timestamp are rejected by then anyway
+	ExpiresAt time.Time
+}
+
+func getNoncesRepo(ctx context.Context, mngDB *mongo.Database) (*NoncesRepo, error) {
+	c := mngDB.Collection("nonces")
+
+	_, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"expiresat": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &NoncesRepo{c: c}, nil
+}
+
+// NoncesRepo remembers nonces of signed requests to reject replays
+type NoncesRepo struct {
+	c *mongo.Collection
+}
+
+// Remember stores the nonce until expiresAt and reports whether it has been already used
+func (r *NoncesRepo) Remember(ctx context.Context, nonce string, expiresAt time.Time) (bool, error) {
+	_, err := r.c.InsertOne(ctx, Nonce{ID: nonce, ExpiresAt: expiresAt})
+	if isDuplicateKey(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
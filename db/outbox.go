@@ -0,0 +1,102 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Outgoing message delivery statuses
+const (
+	OutMsgPending = "pending"
+	OutMsgSent    = "sent"
+	OutMsgFailed  = "failed"
+)
+
+// OutMsg is a queued outgoing telegram message
+type OutMsg struct {
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	ChatID        int64
+	Text          string
+	TextMarkdown  bool
+	Status        string
+	Attempts      int
+	NextAttemptAt int64
+	LastError     string
+	CreatedAt     int64
+	SentAt        int64
+	MessageID     int
+}
+
+func getOutboxRepo(mngDB *mongo.Database) *OutboxRepo {
+	return &OutboxRepo{c: mngDB.Collection("outbox")}
+}
+
+// OutboxRepo provides access to the outgoing messages queue
+type OutboxRepo struct {
+	c *mongo.Collection
+}
+
+// Add queues a message
+func (r *OutboxRepo) Add(ctx context.Context, m OutMsg) error {
+	_, err := r.c.InsertOne(ctx, m)
+
+	return err
+}
+
+// ClaimDue finds the oldest pending message due at now and postpones its next attempt to leaseUntil,
+// so that it is not delivered concurrently
+func (r *OutboxRepo) ClaimDue(ctx context.Context, now int64, leaseUntil int64) (OutMsg, error) {
+	filter := bson.M{"status": OutMsgPending, "nextattemptat": bson.M{"$lte": now}}
+	upd := bson.M{"$set": bson.M{"nextattemptat": leaseUntil}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"createdat": 1}).
+		SetReturnDocument(options.After)
+
+	res := r.c.FindOneAndUpdate(ctx, filter, upd, opts)
+	var m OutMsg
+	if res.Err() != nil {
+		return m, res.Err()
+	}
+
+	if err := res.Decode(&m); err != nil {
+		return m, err
+	}
+
+	return m, nil
+}
+
+// Save saves a message
+func (r *OutboxRepo) Save(ctx context.Context, m OutMsg) error {
+	filter := bson.M{"_id": m.ID}
+	upd := bson.M{"$set": m}
+
+	_, err := r.c.UpdateOne(ctx, filter, upd)
+
+	return err
+}
+
+// CountByStatus returns the number of messages with the given status
+func (r *OutboxRepo) CountByStatus(ctx context.Context, status string) (int64, error) {
+	return r.c.CountDocuments(ctx, bson.M{"status": status})
+}
+
+// GetLastFailed returns up to n most recent undelivered messages
+func (r *OutboxRepo) GetLastFailed(ctx context.Context, n int) ([]OutMsg, error) {
+	opts := options.Find().SetSort(bson.M{"createdat": -1}).SetLimit(int64(n))
+
+	cur, err := r.c.Find(ctx, bson.M{"status": OutMsgFailed}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var msgs []OutMsg
+	if err := cur.All(ctx, &msgs); err != nil {
+		return nil, err
+	}
+
+	return msgs, nil
+}
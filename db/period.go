@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Period is an archived budget period
+type Period struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	Amount    float64
+	StartedAt int64
+	ExpiresAt int64
+}
+
+func getPeriodsRepo(mngDB *mongo.Database) *PeriodsRepo {
+	return &PeriodsRepo{c: mngDB.Collection("periods")}
+}
+
+// PeriodsRepo provides access to past budget periods
+type PeriodsRepo struct {
+	c *mongo.Collection
+}
+
+// Add archives a period
+func (r *PeriodsRepo) Add(ctx context.Context, p Period) error {
+	_, err := r.c.InsertOne(ctx, p)
+
+	return err
+}
+
+// GetLast returns up to n most recent periods, newest first
+func (r *PeriodsRepo) GetLast(ctx context.Context, n int) ([]Period, error) {
+	opts := options.Find().SetSort(bson.M{"startedat": -1}).SetLimit(int64(n))
+
+	cur, err := r.c.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var periods []Period
+	if err := cur.All(ctx, &periods); err != nil {
+		return nil, err
+	}
+
+	return periods, nil
+}
@@ -6,16 +6,36 @@ import (
 
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
 )
 
 type Repo struct {
-	Tokens     *TokensRepo
-	Budget     *BudgetRepo
+	Tokens         *TokensRepo
+	Budget         *BudgetRepo
+	BalanceHistory *BalanceHistoryRepo
+	Periods        *PeriodsRepo
+	Conversations  *ConversationsRepo
+	Chats          *ChatsRepo
+	Updates        *UpdatesRepo
+	Outbox         *OutboxRepo
+	Nonces         *NoncesRepo
+	Transactions   *TransactionsRepo
+	Sessions       *SessionsRepo
+	Webhooks       *WebhooksRepo
+	Deliveries     *DeliveriesRepo
+
+	cli *mongo.Client
 }
 
-func (r *Repo) Close() {
+// Ping checks the connection to the primary
+func (r *Repo) Ping(ctx context.Context) error {
+	return r.cli.Ping(ctx, readpref.Primary())
+}
 
+// Close disconnects from Mongo
+func (r *Repo) Close(ctx context.Context) error {
+	return r.cli.Disconnect(ctx)
 }
 
 func GetRepo(ctx context.Context, mongoURI string) (*Repo, error) {
@@ -38,8 +58,45 @@ func GetRepo(ctx context.Context, mongoURI string) (*Repo, error) {
 
 	db := cli.Database(connStr.Database)
 
+	updatesRepo, err := getUpdatesRepo(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	noncesRepo, err := getNoncesRepo(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	transactionsRepo, err := getTransactionsRepo(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	sessionsRepo, err := getSessionsRepo(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveriesRepo, err := getDeliveriesRepo(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Repo{
-		Tokens:     getTokensRepo(db),
-		Budget:     getBudgetRepo(db),
+		Tokens:         getTokensRepo(db),
+		Budget:         getBudgetRepo(db),
+		BalanceHistory: getBalanceHistoryRepo(db),
+		Periods:        getPeriodsRepo(db),
+		Conversations:  getConversationsRepo(db),
+		Chats:          getChatsRepo(db),
+		Updates:        updatesRepo,
+		Outbox:         getOutboxRepo(db),
+		Nonces:         noncesRepo,
+		Transactions:   transactionsRepo,
+		Sessions:       sessionsRepo,
+		Webhooks:       getWebhooksRepo(db),
+		Deliveries:     deliveriesRepo,
+		cli:            cli,
 	}, nil
 }
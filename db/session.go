@@ -0,0 +1,95 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Session kinds
+const (
+	// SessionLoginLink is a one time link exchanged for a web session
+	SessionLoginLink = "login_link"
+	// SessionWeb is a web dashboard session
+	SessionWeb = "web"
+)
+
+// Session is a login link or a web session, the secret itself is not stored
+type Session struct {
+	// ID is a hash of the secret
+	ID        string `bson:"_id"`
+	Kind      string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func getSessionsRepo(ctx context.Context, mngDB *mongo.Database) (*SessionsRepo, error) {
+	c := mngDB.Collection("sessions")
+
+	_, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"expiresat": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &SessionsRepo{c: c}, nil
+}
+
+// SessionsRepo stores login links and web sessions
+type SessionsRepo struct {
+	c *mongo.Collection
+}
+
+// Add stores a session
+func (r *SessionsRepo) Add(ctx context.Context, s Session) error {
+	_, err := r.c.InsertOne(ctx, s)
+
+	return err
+}
+
+// Get gets a not expired session of the kind
+func (r *SessionsRepo) Get(ctx context.Context, id, kind string) (Session, error) {
+	// expired sessions are deleted by the TTL monitor only once a minute
+	filter := bson.M{"_id": id, "kind": kind, "expiresat": bson.M{"$gt": time.Now()}}
+
+	var s Session
+	res := r.c.FindOne(ctx, filter)
+	if res.Err() != nil {
+		return s, res.Err()
+	}
+
+	if err := res.Decode(&s); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// Take gets and deletes a not expired session of the kind, so it can be used once
+func (r *SessionsRepo) Take(ctx context.Context, id, kind string) (Session, error) {
+	filter := bson.M{"_id": id, "kind": kind, "expiresat": bson.M{"$gt": time.Now()}}
+
+	var s Session
+	res := r.c.FindOneAndDelete(ctx, filter)
+	if res.Err() != nil {
+		return s, res.Err()
+	}
+
+	if err := res.Decode(&s); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// Delete deletes a session
+func (r *SessionsRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.c.DeleteOne(ctx, bson.M{"_id": id})
+
+	return err
+}
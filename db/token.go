@@ -2,17 +2,24 @@ package db
 
 import (
 	"context"
-	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// Token is an API token, the token secret itself is not stored
 type Token struct {
-	ID   string `bson:"_id"`
-	Time time.Time
-	Data []byte
+	// ID is a hash of the token secret
+	ID        string `bson:"_id"`
+	Name      string
+	Scopes    []string
+	CreatedAt int64
+	// ExpiresAt is 0 for tokens without expiration
+	ExpiresAt  int64
+	LastUsedAt int64
+	// Widgets are user defined widget templates by their format names
+	Widgets map[string]string
 }
 
 func getTokensRepo(mngDB *mongo.Database) *TokensRepo {
@@ -40,6 +47,38 @@ func (r *TokensRepo) GetOne(ctx context.Context, id string) (Token, error) {
 	return op, nil
 }
 
+// GetByName gets a token with the given name
+func (r *TokensRepo) GetByName(ctx context.Context, name string) (Token, error) {
+	res := r.c.FindOne(ctx, bson.M{"name": name})
+	var t Token
+	if res.Err() != nil {
+		return t, res.Err()
+	}
+
+	if err := res.Decode(&t); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// GetAll gets all tokens sorted by name
+func (r *TokensRepo) GetAll(ctx context.Context) ([]Token, error) {
+	opts := options.Find().SetSort(bson.M{"name": 1})
+
+	cur, err := r.c.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var tokens []Token
+	if err := cur.All(ctx, &tokens); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
 // Save saves a token
 func (r *TokensRepo) Save(ctx context.Context, t Token) error {
 	filter := bson.M{"_id": t.ID}
@@ -54,3 +93,41 @@ func (r *TokensRepo) Save(ctx context.Context, t Token) error {
 	}
 	return nil
 }
+
+// SetLastUsedAt updates the last usage time of a token
+func (r *TokensRepo) SetLastUsedAt(ctx context.Context, id string, at int64) error {
+	_, err := r.c.UpdateOne(ctx, bson.M{"_id": id}, bson.M{"$set": bson.M{"lastusedat": at}})
+
+	return err
+}
+
+// SetWidget sets a widget template of a token with the given name, an empty template deletes it
+func (r *TokensRepo) SetWidget(ctx context.Context, name, format, tmpl string) error {
+	upd := bson.M{"$set": bson.M{"widgets." + format: tmpl}}
+	if tmpl == "" {
+		upd = bson.M{"$unset": bson.M{"widgets." + format: ""}}
+	}
+
+	res, err := r.c.UpdateOne(ctx, bson.M{"name": name}, upd)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// DeleteByName deletes a token with the given name
+func (r *TokensRepo) DeleteByName(ctx context.Context, name string) error {
+	res, err := r.c.DeleteOne(ctx, bson.M{"name": name})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
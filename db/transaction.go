@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Transaction is an account operation
+type Transaction struct {
+	// ID identifies the source of the transaction, e.g. a hash of an SMS text
+	ID       string `bson:"_id"`
+	At       int64
+	Amount   float64
+	Currency string
+	Merchant string
+	// Balance is the account balance after the transaction, valid if HasBalance
+	Balance    float64
+	HasBalance bool
+	Source     string
+	Text       string
+}
+
+func getTransactionsRepo(ctx context.Context, mngDB *mongo.Database) (*TransactionsRepo, error) {
+	c := mngDB.Collection("transactions")
+
+	_, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{Keys: bson.M{"at": 1}})
+	if err != nil {
+		return nil, err
+	}
+
+	return &TransactionsRepo{c: c}, nil
+}
+
+// TransactionsRepo provides access to the transactions ledger
+type TransactionsRepo struct {
+	c *mongo.Collection
+}
+
+// Add inserts the transaction and reports whether a transaction with the same ID already exists
+func (r *TransactionsRepo) Add(ctx context.Context, t Transaction) (bool, error) {
+	_, err := r.c.InsertOne(ctx, t)
+	if isDuplicateKey(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
+
+// GetRange returns transactions made within [from, to] sorted by time
+func (r *TransactionsRepo) GetRange(ctx context.Context, from, to int64) ([]Transaction, error) {
+	filter := bson.M{"at": bson.M{"$gte": from, "$lte": to}}
+	opts := options.Find().SetSort(bson.D{{Key: "at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cur, err := r.c.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var txs []Transaction
+	if err := cur.All(ctx, &txs); err != nil {
+		return nil, err
+	}
+
+	return txs, nil
+}
+
+// GetLastWithBalance returns the latest transaction made before the given time having the balance
+func (r *TransactionsRepo) GetLastWithBalance(ctx context.Context, before int64) (Transaction, error) {
+	filter := bson.M{"at": bson.M{"$lt": before}, "hasbalance": true}
+	opts := options.FindOne().SetSort(bson.M{"at": -1})
+
+	var t Transaction
+	res := r.c.FindOne(ctx, filter, opts)
+	if res.Err() != nil {
+		return t, res.Err()
+	}
+
+	if err := res.Decode(&t); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// GetLastBySource returns the latest transaction of the source
+func (r *TransactionsRepo) GetLastBySource(ctx context.Context, source string) (Transaction, error) {
+	opts := options.FindOne().SetSort(bson.M{"at": -1})
+
+	var t Transaction
+	res := r.c.FindOne(ctx, bson.M{"source": source}, opts)
+	if res.Err() != nil {
+		return t, res.Err()
+	}
+
+	if err := res.Decode(&t); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
@@ -0,0 +1,51 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// handled updates are forgotten after Telegram stops redelivering them
+const updatesTTL = 24 * time.Hour
+
+// HandledUpdate is a telegram update that has been handled
+type HandledUpdate struct {
+	ID        int `bson:"_id"`
+	HandledAt time.Time
+}
+
+func getUpdatesRepo(ctx context.Context, mngDB *mongo.Database) (*UpdatesRepo, error) {
+	c := mngDB.Collection("updates")
+
+	_, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"handledat": 1},
+		Options: options.Index().SetExpireAfterSeconds(int32(updatesTTL.Seconds())),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UpdatesRepo{c: c}, nil
+}
+
+// UpdatesRepo remembers handled telegram updates
+type UpdatesRepo struct {
+	c *mongo.Collection
+}
+
+// MarkHandled marks the update as handled and reports whether it has been already handled before
+func (r *UpdatesRepo) MarkHandled(ctx context.Context, updateID int) (bool, error) {
+	_, err := r.c.InsertOne(ctx, HandledUpdate{ID: updateID, HandledAt: time.Now()})
+	if isDuplicateKey(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return false, nil
+}
@@ -0,0 +1,169 @@
+package db
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// Webhook is an external URL receiving budget events
+type Webhook struct {
+	ID  primitive.ObjectID `bson:"_id,omitempty"`
+	URL string
+	// Secret signs deliveries, it is stored as is since it is needed to sign
+	Secret string
+	// Events are event types delivered to the webhook, all events if empty
+	Events    []string
+	CreatedAt int64
+}
+
+func getWebhooksRepo(mngDB *mongo.Database) *WebhooksRepo {
+	return &WebhooksRepo{c: mngDB.Collection("webhooks")}
+}
+
+// WebhooksRepo provides access to registered webhooks
+type WebhooksRepo struct {
+	c *mongo.Collection
+}
+
+// Add registers a webhook and returns its ID
+func (r *WebhooksRepo) Add(ctx context.Context, w Webhook) (primitive.ObjectID, error) {
+	res, err := r.c.InsertOne(ctx, w)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	return res.InsertedID.(primitive.ObjectID), nil
+}
+
+// GetAll gets all webhooks in the registration order
+func (r *WebhooksRepo) GetAll(ctx context.Context) ([]Webhook, error) {
+	opts := options.Find().SetSort(bson.M{"createdat": 1})
+
+	cur, err := r.c.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var hooks []Webhook
+	if err := cur.All(ctx, &hooks); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// Delete deletes a webhook
+func (r *WebhooksRepo) Delete(ctx context.Context, id primitive.ObjectID) error {
+	res, err := r.c.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return ErrNotFound
+	}
+
+	return nil
+}
+
+// Webhook delivery statuses
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryFailed    = "failed"
+)
+
+// Delivery is a queued webhook request together with its delivery log
+type Delivery struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty"`
+	WebhookID primitive.ObjectID
+	URL       string
+	Event     string
+	Payload   string
+	Status    string
+	Attempts  int
+	// LastStatusCode is an HTTP status of the last attempt, 0 if there was no response
+	LastStatusCode int
+	LastError      string
+	NextAttemptAt  int64
+	CreatedAt      int64
+	DeliveredAt    int64
+	// ExpiresAt is when the delivery is removed from the log
+	ExpiresAt time.Time
+}
+
+func getDeliveriesRepo(ctx context.Context, mngDB *mongo.Database) (*DeliveriesRepo, error) {
+	c := mngDB.Collection("webhook_deliveries")
+
+	_, err := c.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.M{"expiresat": 1},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &DeliveriesRepo{c: c}, nil
+}
+
+// DeliveriesRepo provides access to the webhook deliveries queue
+type DeliveriesRepo struct {
+	c *mongo.Collection
+}
+
+// Add queues a delivery
+func (r *DeliveriesRepo) Add(ctx context.Context, d Delivery) error {
+	_, err := r.c.InsertOne(ctx, d)
+
+	return err
+}
+
+// ClaimDue finds the oldest pending delivery due at now and postpones its next attempt to leaseUntil,
+// so that it is not delivered concurrently
+func (r *DeliveriesRepo) ClaimDue(ctx context.Context, now int64, leaseUntil int64) (Delivery, error) {
+	filter := bson.M{"status": DeliveryPending, "nextattemptat": bson.M{"$lte": now}}
+	upd := bson.M{"$set": bson.M{"nextattemptat": leaseUntil}}
+	opts := options.FindOneAndUpdate().
+		SetSort(bson.M{"createdat": 1}).
+		SetReturnDocument(options.After)
+
+	res := r.c.FindOneAndUpdate(ctx, filter, upd, opts)
+	var d Delivery
+	if res.Err() != nil {
+		return d, res.Err()
+	}
+
+	if err := res.Decode(&d); err != nil {
+		return d, err
+	}
+
+	return d, nil
+}
+
+// Save saves a delivery
+func (r *DeliveriesRepo) Save(ctx context.Context, d Delivery) error {
+	_, err := r.c.UpdateOne(ctx, bson.M{"_id": d.ID}, bson.M{"$set": d})
+
+	return err
+}
+
+// GetLast returns up to n most recent deliveries
+func (r *DeliveriesRepo) GetLast(ctx context.Context, n int) ([]Delivery, error) {
+	opts := options.Find().SetSort(bson.M{"createdat": -1}).SetLimit(int64(n))
+
+	cur, err := r.c.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var deliveries []Delivery
+	if err := cur.All(ctx, &deliveries); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,40 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+func writeCSV(w io.Writer, txs []budget.Transaction, opts Options) error {
+	cw := csv.NewWriter(w)
+
+	if err := cw.Write([]string{"id", "time", "amount", "currency", "merchant", "balance", "source"}); err != nil {
+		return err
+	}
+
+	for _, tx := range txs {
+		balance := ""
+		if tx.Balance != nil {
+			balance = formatAmount(*tx.Balance)
+		}
+
+		record := []string{
+			tx.ID,
+			time.Unix(tx.At, 0).UTC().Format(time.RFC3339),
+			formatAmount(tx.Amount),
+			currency(tx, opts),
+			tx.Merchant,
+			balance,
+			tx.Source,
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
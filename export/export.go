@@ -0,0 +1,152 @@
+// Package export writes the transactions ledger in formats of accounting tools.
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// Format is an export file format
+type Format string
+
+// Export formats
+const (
+	FormatCSV       Format = "csv"
+	FormatOFX       Format = "ofx"
+	FormatHledger   Format = "hledger"
+	FormatBeancount Format = "beancount"
+)
+
+// Formats are all supported formats
+var Formats = []Format{FormatCSV, FormatOFX, FormatHledger, FormatBeancount}
+
+// ParseFormat parses a format name
+func ParseFormat(s string) (Format, bool) {
+	for _, f := range Formats {
+		if string(f) == strings.ToLower(s) {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// ContentType returns a MIME type of the format
+func (f Format) ContentType() string {
+	switch f {
+	case FormatCSV:
+		return "text/csv"
+	case FormatOFX:
+		return "application/x-ofx"
+	}
+	return "text/plain; charset=utf-8"
+}
+
+// Extension returns a file name extension of the format
+func (f Format) Extension() string {
+	switch f {
+	case FormatHledger:
+		return "journal"
+	case FormatBeancount:
+		return "beancount"
+	}
+	return string(f)
+}
+
+// Options configure an export
+type Options struct {
+	// Currency is the account currency, used for transactions without one
+	Currency string
+	// From and To are the exported range, used by formats declaring it
+	From, To time.Time
+}
+
+// RangeOptions returns options of the requested range, zero bounds are replaced by the exported data range,
+// or by the end of the range if there is no data
+func RangeOptions(from, to int64, txs []budget.Transaction) Options {
+	opts := Options{From: time.Unix(from, 0), To: time.Now()}
+	if to != 0 {
+		opts.To = time.Unix(to, 0)
+	}
+	if from == 0 {
+		opts.From = opts.To
+		if len(txs) > 0 {
+			opts.From = time.Unix(txs[0].At, 0)
+		}
+	}
+
+	return opts
+}
+
+// DefaultCurrency is the currency of the tracked card
+const DefaultCurrency = "GEL"
+
+// Write writes the transactions in the format
+func Write(w io.Writer, f Format, txs []budget.Transaction, opts Options) error {
+	if opts.Currency == "" {
+		opts.Currency = DefaultCurrency
+	}
+
+	switch f {
+	case FormatCSV:
+		return writeCSV(w, txs, opts)
+	case FormatOFX:
+		return writeOFX(w, txs, opts)
+	case FormatHledger:
+		return writeHledger(w, txs, opts)
+	case FormatBeancount:
+		return writeBeancount(w, txs, opts)
+	}
+
+	return fmt.Errorf("unknown format: %s", f)
+}
+
+func currency(tx budget.Transaction, opts Options) string {
+	if tx.Currency == "" {
+		return opts.Currency
+	}
+	return tx.Currency
+}
+
+func formatAmount(v float64) string {
+	return strconv.FormatFloat(v, 'f', 2, 64)
+}
+
+// DateLayout is a layout of dates of an export range
+const DateLayout = "2006-01-02"
+
+// ParseRange parses an export range of dates in UTC, both inclusive, empty dates are returned as 0
+func ParseRange(from, to string) (int64, int64, error) {
+	var fromTS, toTS int64
+
+	if from != "" {
+		t, err := time.Parse(DateLayout, from)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid from date: %w", err)
+		}
+		fromTS = t.Unix()
+	}
+
+	if to != "" {
+		t, err := time.Parse(DateLayout, to)
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid to date: %w", err)
+		}
+		toTS = t.AddDate(0, 0, 1).Unix() - 1
+	}
+
+	if fromTS != 0 && toTS != 0 && toTS < fromTS {
+		return 0, 0, fmt.Errorf("to date is before from date")
+	}
+
+	return fromTS, toTS, nil
+}
+
+// FileName returns a name of an export file of the range
+func FileName(f Format, from, to time.Time) string {
+	return fmt.Sprintf("transactions_%s_%s.%s", from.UTC().Format(DateLayout), to.UTC().Format(DateLayout), f.Extension())
+}
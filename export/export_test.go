@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+func TestWrite(t *testing.T) {
+	balance := 1072.8
+	txs := []budget.Transaction{
+		{ID: "sms:1", At: 1700519450, Amount: -1, Merchant: "LTD MP DEVELOPMENT", Balance: &balance, Source: "sms"},
+		{ID: "sms:2", At: 1700605850, Amount: 100, Currency: "USD", Source: "sms"},
+	}
+	opts := Options{From: time.Unix(1700000000, 0), To: time.Unix(1701000000, 0)}
+
+	tests := []struct {
+		format Format
+		want   []string
+	}{
+		{FormatCSV, []string{
+			"id,time,amount,currency,merchant,balance,source\n",
+			"sms:1,2023-11-20T22:30:50Z,-1.00,GEL,LTD MP DEVELOPMENT,1072.80,sms\n",
+			"sms:2,2023-11-21T22:30:50Z,100.00,USD,,,sms\n",
+		}},
+		{FormatOFX, []string{
+			"<TRNTYPE>DEBIT</TRNTYPE>",
+			"<DTPOSTED>20231120223050</DTPOSTED>",
+			"<TRNAMT>-1.00</TRNAMT>",
+			"<NAME>LTD MP DEVELOPMENT</NAME>",
+			"<TRNTYPE>CREDIT</TRNTYPE>",
+			"<MEMO>100.00 USD</MEMO>",
+			"<BALAMT>1072.80</BALAMT>",
+			"<DTSTART>20231114221320</DTSTART>",
+		}},
+		{FormatHledger, []string{
+			"2023-11-20 LTD MP DEVELOPMENT  ; id:sms:1, balance:1072.80 GEL\n    assets:card  -1.00 GEL\n    expenses:unknown\n",
+			"    assets:card  100.00 USD\n    income:unknown\n",
+		}},
+		{FormatBeancount, []string{
+			"2023-11-20 open Assets:Card\n",
+			"2023-11-20 * \"LTD MP DEVELOPMENT\"\n  id: \"sms:1\"\n  Assets:Card  -1.00 GEL\n  Expenses:Unknown\n",
+			"  Income:Unknown\n",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := Write(&buf, tt.format, txs, opts); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, s := range tt.want {
+				if !strings.Contains(buf.String(), s) {
+					t.Errorf("%q not found in:\n%s", s, buf.String())
+				}
+			}
+		})
+	}
+}
+
+func TestRangeOptions(t *testing.T) {
+	txs := []budget.Transaction{{At: 1700000000}, {At: 1700100000}}
+
+	if opts := RangeOptions(0, 1700200000, txs); opts.From.Unix() != 1700000000 || opts.To.Unix() != 1700200000 {
+		t.Errorf("unexpected range %s - %s", opts.From, opts.To)
+	}
+	if opts := RangeOptions(1690000000, 0, txs); opts.From.Unix() != 1690000000 || opts.To.Before(time.Now().Add(-time.Minute)) {
+		t.Errorf("unexpected range %s - %s", opts.From, opts.To)
+	}
+
+	// an empty ledger is not exported since 1970
+	if opts := RangeOptions(0, 1700200000, nil); opts.From.Unix() != 1700200000 {
+		t.Errorf("unexpected start of an empty range %s", opts.From)
+	}
+	if opts := RangeOptions(0, 0, nil); !opts.From.Equal(opts.To) {
+		t.Errorf("unexpected empty range %s - %s", opts.From, opts.To)
+	}
+}
@@ -0,0 +1,91 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// Accounts of plain text accounting journals
+const (
+	journalCard     = "assets:card"
+	journalExpenses = "expenses:unknown"
+	journalIncome   = "income:unknown"
+)
+
+// counterAccount returns where the money went to or came from
+func counterAccount(tx budget.Transaction) string {
+	if tx.Amount > 0 {
+		return journalIncome
+	}
+	return journalExpenses
+}
+
+func writeHledger(w io.Writer, txs []budget.Transaction, opts Options) error {
+	for _, tx := range txs {
+		cur := currency(tx, opts)
+
+		comment := "id:" + tx.ID
+		if tx.Balance != nil {
+			comment += fmt.Sprintf(", balance:%s %s", formatAmount(*tx.Balance), opts.Currency)
+		}
+
+		_, err := fmt.Fprintf(w, "%s %s  ; %s\n    %s  %s %s\n    %s\n\n",
+			time.Unix(tx.At, 0).UTC().Format("2006-01-02"),
+			oneLine(tx.Merchant),
+			comment,
+			journalCard, formatAmount(tx.Amount), cur,
+			counterAccount(tx),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeBeancount(w io.Writer, txs []budget.Transaction, opts Options) error {
+	if len(txs) > 0 {
+		opened := time.Unix(txs[0].At, 0).UTC().Format("2006-01-02")
+		for _, account := range []string{journalCard, journalExpenses, journalIncome} {
+			if _, err := fmt.Fprintf(w, "%s open %s\n", opened, beancountAccount(account)); err != nil {
+				return err
+			}
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+
+	for _, tx := range txs {
+		_, err := fmt.Fprintf(w, "%s * %q\n  id: %q\n  %s  %s %s\n  %s\n\n",
+			time.Unix(tx.At, 0).UTC().Format("2006-01-02"),
+			oneLine(tx.Merchant),
+			tx.ID,
+			beancountAccount(journalCard), formatAmount(tx.Amount), currency(tx, opts),
+			beancountAccount(counterAccount(tx)),
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// beancountAccount converts "assets:card" to "Assets:Card"
+func beancountAccount(account string) string {
+	parts := strings.Split(account, ":")
+	for i, p := range parts {
+		parts[i] = strings.ToUpper(p[:1]) + p[1:]
+	}
+	return strings.Join(parts, ":")
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
@@ -0,0 +1,125 @@
+package export
+
+import (
+	"encoding/xml"
+	"io"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// ofxHeader is a header of an OFX 2.2 document
+const ofxHeader = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+`
+
+// ofxNameLen is a maximal length of the NAME element
+const ofxNameLen = 32
+
+type ofxStatus struct {
+	Code     int    `xml:"CODE"`
+	Severity string `xml:"SEVERITY"`
+}
+
+type ofxTransaction struct {
+	Type   string `xml:"TRNTYPE"`
+	Posted string `xml:"DTPOSTED"`
+	Amount string `xml:"TRNAMT"`
+	FITID  string `xml:"FITID"`
+	Name   string `xml:"NAME,omitempty"`
+	Memo   string `xml:"MEMO,omitempty"`
+}
+
+type ofxBalance struct {
+	Amount string `xml:"BALAMT"`
+	AsOf   string `xml:"DTASOF"`
+}
+
+type ofxDocument struct {
+	XMLName xml.Name `xml:"OFX"`
+	SignOn  struct {
+		Status   ofxStatus `xml:"STATUS"`
+		DTServer string    `xml:"DTSERVER"`
+		Language string    `xml:"LANGUAGE"`
+	} `xml:"SIGNONMSGSRSV1>SONRS"`
+	Statement struct {
+		TrnUID   string    `xml:"TRNUID"`
+		Status   ofxStatus `xml:"STATUS"`
+		Currency string    `xml:"STMTRS>CURDEF"`
+		Account  struct {
+			BankID string `xml:"BANKID"`
+			AcctID string `xml:"ACCTID"`
+			Type   string `xml:"ACCTTYPE"`
+		} `xml:"STMTRS>BANKACCTFROM"`
+		List struct {
+			Start        string           `xml:"DTSTART"`
+			End          string           `xml:"DTEND"`
+			Transactions []ofxTransaction `xml:"STMTTRN"`
+		} `xml:"STMTRS>BANKTRANLIST"`
+		Balance *ofxBalance `xml:"STMTRS>LEDGERBAL,omitempty"`
+	} `xml:"BANKMSGSRSV1>STMTTRNRS"`
+}
+
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405")
+}
+
+func writeOFX(w io.Writer, txs []budget.Transaction, opts Options) error {
+	var doc ofxDocument
+	doc.SignOn.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	doc.SignOn.DTServer = ofxTime(time.Now())
+	doc.SignOn.Language = "ENG"
+
+	st := &doc.Statement
+	st.TrnUID = "1"
+	st.Status = ofxStatus{Code: 0, Severity: "INFO"}
+	st.Currency = opts.Currency
+	st.Account.BankID = "alfabooker"
+	st.Account.AcctID = "card"
+	st.Account.Type = "CHECKING"
+	st.List.Start = ofxTime(opts.From)
+	st.List.End = ofxTime(opts.To)
+
+	for _, tx := range txs {
+		trn := ofxTransaction{
+			Type:   "DEBIT",
+			Posted: ofxTime(time.Unix(tx.At, 0)),
+			Amount: formatAmount(tx.Amount),
+			FITID:  tx.ID,
+			Name:   truncate(oneLine(tx.Merchant), ofxNameLen),
+		}
+		if tx.Amount > 0 {
+			trn.Type = "CREDIT"
+		}
+		// OFX amounts are in the account currency
+		if cur := currency(tx, opts); cur != opts.Currency {
+			trn.Memo = formatAmount(tx.Amount) + " " + cur
+		}
+		st.List.Transactions = append(st.List.Transactions, trn)
+
+		if tx.Balance != nil {
+			st.Balance = &ofxBalance{Amount: formatAmount(*tx.Balance), AsOf: ofxTime(time.Unix(tx.At, 0))}
+		}
+	}
+
+	if _, err := io.WriteString(w, ofxHeader); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
@@ -0,0 +1,551 @@
+package i18n
+
+// Key identifies a message in the catalogue
+type Key string
+
+// Message keys
+const (
+	Help Key = "help"
+
+	Stat          Key = "stat"
+	ChartCaption  Key = "chart_caption"
+	ChartBalance  Key = "chart_balance"
+	ChartNoBudget Key = "chart_no_budget"
+	ControllerErr Key = "controller_err"
+
+	Status         Key = "status"
+	StatusDisabled Key = "status_disabled"
+
+	OutboxStatus Key = "outbox_status"
+	OutboxFailed Key = "outbox_failed"
+
+	ExportUsage   Key = "export_usage"
+	ExportEmpty   Key = "export_empty"
+	ExportCaption Key = "export_caption"
+
+	StatementUnsupported Key = "statement_unsupported"
+	StatementInvalid     Key = "statement_invalid"
+	ReconcileReport      Key = "reconcile_report"
+	ReconcileAdded       Key = "reconcile_added"
+	ReconcileMismatch    Key = "reconcile_mismatch"
+	ReconcileUnmatched   Key = "reconcile_unmatched"
+
+	TokenUsage    Key = "token_usage"
+	TokenIssued   Key = "token_issued"
+	TokenExists   Key = "token_exists"
+	TokenNotFound Key = "token_not_found"
+	TokenRevoked  Key = "token_revoked"
+	TokenNone     Key = "token_none"
+	TokenListItem Key = "token_list_item"
+
+	WebhookUsage     Key = "webhook_usage"
+	WebhookAdded     Key = "webhook_added"
+	WebhookNone      Key = "webhook_none"
+	WebhookListItem  Key = "webhook_list_item"
+	WebhookAllEvents Key = "webhook_all_events"
+	WebhookRemoved   Key = "webhook_removed"
+	WebhookNotFound  Key = "webhook_not_found"
+	WebhookLogItem   Key = "webhook_log_item"
+	WebhookLogEmpty  Key = "webhook_log_empty"
+
+	SMSConfirm  Key = "sms_confirm"
+	SMSBalance  Key = "sms_balance"
+	SMSNoTime   Key = "sms_no_time"
+	SMSOlder    Key = "sms_older"
+	SMSNoAmount Key = "sms_no_amount"
+	SMSApplied  Key = "sms_applied"
+	SMSRecorded Key = "sms_recorded"
+	SMSKnown    Key = "sms_known"
+
+	LoginLink  Key = "login_link"
+	LoginNoURL Key = "login_no_url"
+
+	WebTitle          Key = "web_title"
+	WebBalance        Key = "web_balance"
+	WebCard           Key = "web_card"
+	WebCash           Key = "web_cash"
+	WebReserved       Key = "web_reserved"
+	WebDeviation      Key = "web_deviation"
+	WebDaysLeft       Key = "web_days_left"
+	WebDailyAverage   Key = "web_daily_average"
+	WebTodayAllowance Key = "web_today_allowance"
+	WebPeriod         Key = "web_period"
+	WebNoBudget       Key = "web_no_budget"
+	WebTransactions   Key = "web_transactions"
+	WebNoTransactions Key = "web_no_transactions"
+	WebLogout         Key = "web_logout"
+	WebLoginRequired  Key = "web_login_required"
+	WebLoginExpired   Key = "web_login_expired"
+	WebLoginConfirm   Key = "web_login_confirm"
+	WebLogin          Key = "web_login"
+
+	LangChoose Key = "lang_choose"
+	LangSet    Key = "lang_set"
+
+	BtnConfirm Key = "btn_confirm"
+	BtnCancel  Key = "btn_cancel"
+
+	WizardCancelled     Key = "wizard_cancelled"
+	WizardEnterNumber   Key = "wizard_enter_number"
+	WizardUseButtons    Key = "wizard_use_buttons"
+	WizardInvalidDays   Key = "wizard_invalid_days"
+	WizardBudgetDays    Key = "wizard_budget_days"
+	WizardBudgetAmount  Key = "wizard_budget_amount"
+	WizardBudgetReserve Key = "wizard_budget_reserve"
+	WizardBudgetConfirm Key = "wizard_budget_confirm"
+	WizardCashOnHand    Key = "wizard_cash_on_hand"
+	WizardCardBalance   Key = "wizard_card_balance"
+)
+
+var catalogue = map[Lang]map[Key]string{
+	En: {
+		Help: `?           - show statistics
+
+/chart [num]  - show balance chart of the current and <num> past periods
+
+/export [format] [from] [to] - export transactions (csv, ofx, hledger, beancount)
+
+/new          - start new budget step by step
+
+/reconcile    - enter cash on hand and card balance step by step
+
+/cancel       - cancel the current step by step dialog
+
+/lang [code]  - choose language (en, ru, ka)
+
+/status [off] - pin a live status message to the chat (or stop updating it)
+
+/outbox       - show delivery status of notifications
+
+a bank SMS    - paste or forward it to record the operation
+
+a CSV or OFX file - reconcile a bank statement with recorded operations
+
+/token        - manage API tokens (new, list, revoke)
+
+/login        - get a link to the web dashboard
+
+/webhook      - manage webhooks receiving budget events (add, list, remove, log)
+
+start <num>   - start new budget tracking for <num> days
+
+card          - set amount on card to <num>
+
+cash <num>    - set amount of cash to <num>
+
+add cash <num> - add/decrease cache by <num>
+
+align <num>   - decrease budget amount by <num> and it's duration proportionately'
+
+reserve <num> - set reserved balance value (will not be counted in total balance)
+
+add budget   - add/decrease budget by <num>
+`,
+		Stat: `card: %s, cash: %s, reserved: %s
+total: %s
+%s from estimated balance
+%s days left
+%s avg daily spending`,
+		ChartCaption:  "%s - %s, budget: %s",
+		ChartBalance:  "balance: %s, estimated: %s",
+		ChartNoBudget: "No budget to chart yet, start one with /new",
+		ControllerErr: "⚠️ controller: %s, error:\n%s\ncontext:\n%+v\n",
+
+		Status: `📌 balance: %s (%s from estimated)
+days left: %s
+today's allowance: %s
+updated: %s`,
+		StatusDisabled: "The status message will not be updated anymore",
+
+		OutboxStatus: "notifications pending: %d, undelivered: %d",
+		OutboxFailed: "%s, %d attempts: %s",
+
+		ExportUsage:   "/export [csv|ofx|hledger|beancount] [from YYYY-MM-DD] [to YYYY-MM-DD]",
+		ExportEmpty:   "No transactions in this range",
+		ExportCaption: "%d transactions, %s - %s",
+
+		StatementUnsupported: "Send a bank statement as a CSV or OFX file up to 10 MB",
+		StatementInvalid:     "Could not read the statement: %s",
+		ReconcileReport:      "Statement: %d rows, %d matched, %d added, %d mismatched, %d recorded operations are missing in the statement",
+		ReconcileAdded:       "+ %s %s %s",
+		ReconcileMismatch:    "≠ %s %s: %s in the statement, %s recorded",
+		ReconcileUnmatched:   "? %s %s %s",
+
+		TokenUsage: `/token new <name> <scopes> [days] - issue an API token
+/token list - list API tokens
+/token revoke <name> - revoke an API token
+scopes (comma separated): %s`,
+		TokenIssued:   "Token %s (%s):\n%s\nIt is shown only once.",
+		TokenExists:   "Token %s already exists",
+		TokenNotFound: "Token %s not found",
+		TokenRevoked:  "Token %s revoked",
+		TokenNone:     "No API tokens",
+		TokenListItem: "%s [%s], expires: %s, last used: %s",
+
+		WebhookUsage: `/webhook add <url> [events] - register a webhook
+/webhook list - list webhooks
+/webhook remove <id> - remove a webhook
+/webhook log - show recent deliveries
+events (comma separated, all by default): %s`,
+		WebhookAdded:     "Webhook %s for %s (%s). Requests are signed with the secret:\n%s\nIt is shown only once.",
+		WebhookNone:      "No webhooks",
+		WebhookListItem:  "%s %s (%s)",
+		WebhookAllEvents: "all events",
+		WebhookRemoved:   "Webhook %s removed",
+		WebhookNotFound:  "Webhook %s not found",
+		WebhookLogItem:   "%s %s → %s: %s, %d attempts",
+		WebhookLogEmpty:  "No webhook deliveries",
+
+		SMSConfirm:  "Recognised a bank SMS: %s\n%s\nApply it?",
+		SMSBalance:  "balance %s",
+		SMSNoTime:   "The SMS has no time, it is recorded as made now",
+		SMSOlder:    "The SMS is older than the balance updated at %s, only the transaction is recorded",
+		SMSNoAmount: "The SMS has no amount, it is derived from the previous balance",
+		SMSApplied:  "✅ %s, %s\ncard: %s, total: %s",
+		SMSRecorded: "Recorded %s, %s. The balance is newer and has not been changed",
+		SMSKnown:    "This SMS has been already recorded",
+
+		LoginLink:  "Dashboard login link, valid for 10 minutes and only once:\n%s",
+		LoginNoURL: "The dashboard URL is not configured",
+
+		WebTitle:          "Budget",
+		WebBalance:        "Balance",
+		WebCard:           "Card",
+		WebCash:           "Cash",
+		WebReserved:       "Reserved",
+		WebDeviation:      "From estimated",
+		WebDaysLeft:       "Days left",
+		WebDailyAverage:   "Avg daily spending",
+		WebTodayAllowance: "Today's allowance",
+		WebPeriod:         "Period",
+		WebNoBudget:       "No budget has been started yet",
+		WebTransactions:   "Recent transactions",
+		WebNoTransactions: "No transactions in this period",
+		WebLogout:         "Log out",
+		WebLoginRequired:  "Send /login to the bot to get a login link",
+		WebLoginExpired:   "The login link has expired or has been already used, send /login to the bot to get a new one",
+		WebLoginConfirm:   "Log in to the dashboard on this device?",
+		WebLogin:          "Log in",
+
+		LangChoose: "Choose a language",
+		LangSet:    "Language set to English",
+
+		BtnConfirm: "✅ Confirm",
+		BtnCancel:  "Cancel",
+
+		WizardCancelled:     "Cancelled",
+		WizardEnterNumber:   "Please enter a number or /cancel",
+		WizardUseButtons:    "Please confirm or cancel using the buttons above",
+		WizardInvalidDays:   "Please enter a whole positive number of days",
+		WizardBudgetDays:    "How many days should the new budget last?",
+		WizardBudgetAmount:  "What is the budget amount? Available (card + cash - reserved): %s",
+		WizardBudgetReserve: "How much should be kept reserved? Currently: %s",
+		WizardBudgetConfirm: "Start a new budget of %s for %s days with %s reserved?",
+		WizardCashOnHand:    "How much cash do you have on hand? Recorded: %s",
+		WizardCardBalance:   "What is your card balance? Recorded: %s",
+	},
+	Ru: {
+		Help: `?             - показать статистику
+
+/chart [num]  - график баланса текущего и <num> прошлых периодов
+
+/export [format] [from] [to] - выгрузить операции (csv, ofx, hledger, beancount)
+
+/new          - начать новый бюджет по шагам
+
+/reconcile    - ввести наличные и баланс карты по шагам
+
+/cancel       - отменить текущий пошаговый диалог
+
+/lang [code]  - выбрать язык (en, ru, ka)
+
+/status [off] - закрепить обновляемое сообщение со статусом (или перестать его обновлять)
+
+/outbox       - показать статус доставки уведомлений
+
+SMS банка     - вставьте или перешлите его, чтобы записать операцию
+
+файл CSV или OFX - сверить банковскую выписку с записанными операциями
+
+/token        - управление API токенами (new, list, revoke)
+
+/login        - получить ссылку на веб-панель
+
+/webhook      - управление вебхуками событий бюджета (add, list, remove, log)
+
+start <num>   - начать новый бюджет на <num> дней
+
+card <num>    - установить баланс карты <num>
+
+cash <num>    - установить сумму наличных <num>
+
+add cash <num> - увеличить/уменьшить наличные на <num>
+
+align <num>   - уменьшить бюджет на <num> и пропорционально его длительность
+
+reserve <num> - установить резерв (не учитывается в общем балансе)
+
+add budget <num> - увеличить/уменьшить бюджет на <num>
+`,
+		Stat: `карта: %s, наличные: %s, резерв: %s
+всего: %s
+%s от расчётного баланса
+осталось дней: %s
+%s в среднем тратится в день`,
+		ChartCaption:  "%s - %s, бюджет: %s",
+		ChartBalance:  "баланс: %s, расчётный: %s",
+		ChartNoBudget: "Пока нет бюджета для графика, начните его командой /new",
+		ControllerErr: "⚠️ контроллер: %s, ошибка:\n%s\nконтекст:\n%+v\n",
+
+		Status: `📌 баланс: %s (%s от расчётного)
+осталось дней: %s
+можно потратить сегодня: %s
+обновлено: %s`,
+		StatusDisabled: "Сообщение со статусом больше не будет обновляться",
+
+		OutboxStatus: "уведомлений в очереди: %d, не доставлено: %d",
+		OutboxFailed: "%s, попыток %d: %s",
+
+		ExportUsage:   "/export [csv|ofx|hledger|beancount] [с YYYY-MM-DD] [по YYYY-MM-DD]",
+		ExportEmpty:   "В этом диапазоне нет операций",
+		ExportCaption: "операций: %d, %s - %s",
+
+		StatementUnsupported: "Отправьте банковскую выписку файлом CSV или OFX до 10 МБ",
+		StatementInvalid:     "Не удалось прочитать выписку: %s",
+		ReconcileReport:      "Выписка: строк %d, совпало %d, добавлено %d, расхождений %d, записанных операций нет в выписке: %d",
+		ReconcileAdded:       "+ %s %s %s",
+		ReconcileMismatch:    "≠ %s %s: в выписке %s, записано %s",
+		ReconcileUnmatched:   "? %s %s %s",
+
+		TokenUsage: `/token new <name> <scopes> [days] - выпустить API токен
+/token list - список API токенов
+/token revoke <name> - отозвать API токен
+права (через запятую): %s`,
+		TokenIssued:   "Токен %s (%s):\n%s\nОн показывается только один раз.",
+		TokenExists:   "Токен %s уже существует",
+		TokenNotFound: "Токен %s не найден",
+		TokenRevoked:  "Токен %s отозван",
+		TokenNone:     "API токенов нет",
+		TokenListItem: "%s [%s], истекает: %s, использован: %s",
+
+		WebhookUsage: `/webhook add <url> [events] - зарегистрировать вебхук
+/webhook list - список вебхуков
+/webhook remove <id> - удалить вебхук
+/webhook log - последние доставки
+события (через запятую, по умолчанию все): %s`,
+		WebhookAdded:     "Вебхук %s для %s (%s). Запросы подписываются секретом:\n%s\nОн показывается только один раз.",
+		WebhookNone:      "Нет вебхуков",
+		WebhookListItem:  "%s %s (%s)",
+		WebhookAllEvents: "все события",
+		WebhookRemoved:   "Вебхук %s удалён",
+		WebhookNotFound:  "Вебхук %s не найден",
+		WebhookLogItem:   "%s %s → %s: %s, попыток: %d",
+		WebhookLogEmpty:  "Доставок вебхуков не было",
+
+		SMSConfirm:  "Распознано SMS банка: %s\n%s\nПрименить?",
+		SMSBalance:  "баланс %s",
+		SMSNoTime:   "В SMS нет времени, операция записывается текущим временем",
+		SMSOlder:    "SMS старше баланса, обновлённого %s, записывается только операция",
+		SMSNoAmount: "В SMS нет суммы, она вычисляется по предыдущему балансу",
+		SMSApplied:  "✅ %s, %s\nкарта: %s, всего: %s",
+		SMSRecorded: "Записано %s, %s. Баланс новее и не изменён",
+		SMSKnown:    "Это SMS уже записано",
+
+		LoginLink:  "Ссылка для входа в панель, действует 10 минут и только один раз:\n%s",
+		LoginNoURL: "Адрес панели не настроен",
+
+		WebTitle:          "Бюджет",
+		WebBalance:        "Баланс",
+		WebCard:           "Карта",
+		WebCash:           "Наличные",
+		WebReserved:       "Резерв",
+		WebDeviation:      "От расчётного",
+		WebDaysLeft:       "Осталось дней",
+		WebDailyAverage:   "Средние траты в день",
+		WebTodayAllowance: "Можно потратить сегодня",
+		WebPeriod:         "Период",
+		WebNoBudget:       "Бюджет ещё не начат",
+		WebTransactions:   "Последние операции",
+		WebNoTransactions: "Нет операций за этот период",
+		WebLogout:         "Выйти",
+		WebLoginRequired:  "Отправьте боту /login, чтобы получить ссылку для входа",
+		WebLoginExpired:   "Ссылка для входа устарела или уже использована, отправьте боту /login, чтобы получить новую",
+		WebLoginConfirm:   "Войти в панель на этом устройстве?",
+		WebLogin:          "Войти",
+
+		LangChoose: "Выберите язык",
+		LangSet:    "Выбран русский язык",
+
+		BtnConfirm: "✅ Подтвердить",
+		BtnCancel:  "Отмена",
+
+		WizardCancelled:     "Отменено",
+		WizardEnterNumber:   "Введите число или /cancel",
+		WizardUseButtons:    "Подтвердите или отмените кнопками выше",
+		WizardInvalidDays:   "Введите целое положительное число дней",
+		WizardBudgetDays:    "На сколько дней новый бюджет?",
+		WizardBudgetAmount:  "Какая сумма бюджета? Доступно (карта + наличные - резерв): %s",
+		WizardBudgetReserve: "Сколько оставить в резерве? Сейчас: %s",
+		WizardBudgetConfirm: "Начать бюджет %s на %s дней с резервом %s?",
+		WizardCashOnHand:    "Сколько у вас наличных? Записано: %s",
+		WizardCardBalance:   "Какой баланс на карте? Записано: %s",
+	},
+	Ka: {
+		Help: `?             - სტატისტიკის ჩვენება
+
+/chart [num]  - მიმდინარე და <num> წინა პერიოდის ბალანსის გრაფიკი
+
+/export [format] [from] [to] - ოპერაციების ექსპორტი (csv, ofx, hledger, beancount)
+
+/new          - ახალი ბიუჯეტის დაწყება ნაბიჯ-ნაბიჯ
+
+/reconcile    - ნაღდი ფულისა და ბარათის ბალანსის შეყვანა ნაბიჯ-ნაბიჯ
+
+/cancel       - მიმდინარე დიალოგის გაუქმება
+
+/lang [code]  - ენის არჩევა (en, ru, ka)
+
+/status [off] - განახლებადი სტატუსის შეტყობინების მიმაგრება (ან განახლების შეწყვეტა)
+
+/outbox       - შეტყობინებების მიწოდების სტატუსი
+
+ბანკის SMS    - ჩასვით ან გადმოგზავნეთ ოპერაციის ჩასაწერად
+
+CSV ან OFX ფაილი - საბანკო ამონაწერის შედარება ჩაწერილ ოპერაციებთან
+
+/token        - API ტოკენების მართვა (new, list, revoke)
+
+/login        - ვებ პანელის ბმულის მიღება
+
+/webhook      - ბიუჯეტის მოვლენების ვებჰუკების მართვა (add, list, remove, log)
+
+start <num>   - ახალი ბიუჯეტის დაწყება <num> დღით
+
+card <num>    - ბარათის ბალანსის დაყენება <num>
+
+cash <num>    - ნაღდი ფულის დაყენება <num>
+
+add cash <num> - ნაღდი ფულის გაზრდა/შემცირება <num>-ით
+
+align <num>   - ბიუჯეტის შემცირება <num>-ით და ხანგრძლივობის პროპორციულად შემცირება
+
+reserve <num> - რეზერვის დაყენება (არ ითვლება საერთო ბალანსში)
+
+add budget <num> - ბიუჯეტის გაზრდა/შემცირება <num>-ით
+`,
+		Stat: `ბარათი: %s, ნაღდი: %s, რეზერვი: %s
+სულ: %s
+%s სავარაუდო ბალანსიდან
+დარჩენილი დღეები: %s
+%s საშუალო დღიური ხარჯი`,
+		ChartCaption:  "%s - %s, ბიუჯეტი: %s",
+		ChartBalance:  "ბალანსი: %s, სავარაუდო: %s",
+		ChartNoBudget: "გრაფიკისთვის ბიუჯეტი ჯერ არ არის, დაიწყეთ /new ბრძანებით",
+		ControllerErr: "⚠️ კონტროლერი: %s, შეცდომა:\n%s\nკონტექსტი:\n%+v\n",
+
+		Status: `📌 ბალანსი: %s (%s სავარაუდოდან)
+დარჩენილი დღეები: %s
+დღეს შეგიძლიათ დახარჯოთ: %s
+განახლდა: %s`,
+		StatusDisabled: "სტატუსის შეტყობინება აღარ განახლდება",
+
+		OutboxStatus: "რიგში შეტყობინებები: %d, მიუწოდებელი: %d",
+		OutboxFailed: "%s, %d მცდელობა: %s",
+
+		ExportUsage:   "/export [csv|ofx|hledger|beancount] [დან YYYY-MM-DD] [მდე YYYY-MM-DD]",
+		ExportEmpty:   "ამ პერიოდში ოპერაციები არ არის",
+		ExportCaption: "ოპერაციები: %d, %s - %s",
+
+		StatementUnsupported: "გამოგზავნეთ საბანკო ამონაწერი CSV ან OFX ფაილად 10 მბ-მდე",
+		StatementInvalid:     "ამონაწერის წაკითხვა ვერ მოხერხდა: %s",
+		ReconcileReport:      "ამონაწერი: სტრიქონი %d, დაემთხვა %d, დაემატა %d, შეუსაბამობა %d, ჩაწერილი ოპერაციები, რომლებიც ამონაწერში არ არის: %d",
+		ReconcileAdded:       "+ %s %s %s",
+		ReconcileMismatch:    "≠ %s %s: ამონაწერში %s, ჩაწერილია %s",
+		ReconcileUnmatched:   "? %s %s %s",
+
+		TokenUsage: `/token new <name> <scopes> [days] - API ტოკენის გაცემა
+/token list - API ტოკენების სია
+/token revoke <name> - API ტოკენის გაუქმება
+უფლებები (მძიმით გამოყოფილი): %s`,
+		TokenIssued:   "ტოკენი %s (%s):\n%s\nის მხოლოდ ერთხელ ჩანს.",
+		TokenExists:   "ტოკენი %s უკვე არსებობს",
+		TokenNotFound: "ტოკენი %s ვერ მოიძებნა",
+		TokenRevoked:  "ტოკენი %s გაუქმებულია",
+		TokenNone:     "API ტოკენები არ არის",
+		TokenListItem: "%s [%s], ვადა: %s, ბოლოს გამოყენებული: %s",
+
+		WebhookUsage: `/webhook add <url> [events] - ვებჰუკის რეგისტრაცია
+/webhook list - ვებჰუკების სია
+/webhook remove <id> - ვებჰუკის წაშლა
+/webhook log - ბოლო მიწოდებები
+მოვლენები (მძიმით, ნაგულისხმევად ყველა): %s`,
+		WebhookAdded:     "ვებჰუკი %s მისამართისთვის %s (%s). მოთხოვნები ხელმოწერილია საიდუმლოთი:\n%s\nის მხოლოდ ერთხელ ჩანს.",
+		WebhookNone:      "ვებჰუკები არ არის",
+		WebhookListItem:  "%s %s (%s)",
+		WebhookAllEvents: "ყველა მოვლენა",
+		WebhookRemoved:   "ვებჰუკი %s წაშლილია",
+		WebhookNotFound:  "ვებჰუკი %s ვერ მოიძებნა",
+		WebhookLogItem:   "%s %s → %s: %s, მცდელობა: %d",
+		WebhookLogEmpty:  "ვებჰუკების მიწოდებები არ ყოფილა",
+
+		SMSConfirm:  "ამოცნობილია ბანკის SMS: %s\n%s\nგამოვიყენო?",
+		SMSBalance:  "ბალანსი %s",
+		SMSNoTime:   "SMS-ში დრო არ არის, ოპერაცია ჩაიწერება მიმდინარე დროით",
+		SMSOlder:    "SMS უფრო ძველია, ვიდრე ბალანსი, განახლებული %s, ჩაიწერება მხოლოდ ოპერაცია",
+		SMSNoAmount: "SMS-ში თანხა არ არის, ის გამოითვლება წინა ბალანსით",
+		SMSApplied:  "✅ %s, %s\nბარათი: %s, სულ: %s",
+		SMSRecorded: "ჩაწერილია %s, %s. ბალანსი უფრო ახალია და არ შეცვლილა",
+		SMSKnown:    "ეს SMS უკვე ჩაწერილია",
+
+		LoginLink:  "პანელში შესვლის ბმული, მოქმედებს 10 წუთი და მხოლოდ ერთხელ:\n%s",
+		LoginNoURL: "პანელის მისამართი არ არის მითითებული",
+
+		WebTitle:          "ბიუჯეტი",
+		WebBalance:        "ბალანსი",
+		WebCard:           "ბარათი",
+		WebCash:           "ნაღდი",
+		WebReserved:       "რეზერვი",
+		WebDeviation:      "სავარაუდოდან",
+		WebDaysLeft:       "დარჩენილი დღე",
+		WebDailyAverage:   "საშუალო დღიური ხარჯი",
+		WebTodayAllowance: "დღეს შეიძლება დაიხარჯოს",
+		WebPeriod:         "პერიოდი",
+		WebNoBudget:       "ბიუჯეტი ჯერ არ დაწყებულა",
+		WebTransactions:   "ბოლო ოპერაციები",
+		WebNoTransactions: "ამ პერიოდში ოპერაციები არ არის",
+		WebLogout:         "გასვლა",
+		WebLoginRequired:  "გაუგზავნეთ ბოტს /login შესვლის ბმულის მისაღებად",
+		WebLoginExpired:   "შესვლის ბმულს ვადა გაუვიდა ან უკვე გამოყენებულია, გაუგზავნეთ ბოტს /login ახლის მისაღებად",
+		WebLoginConfirm:   "შეხვიდეთ პანელში ამ მოწყობილობიდან?",
+		WebLogin:          "შესვლა",
+
+		LangChoose: "აირჩიეთ ენა",
+		LangSet:    "არჩეულია ქართული ენა",
+
+		BtnConfirm: "✅ დადასტურება",
+		BtnCancel:  "გაუქმება",
+
+		WizardCancelled:     "გაუქმებულია",
+		WizardEnterNumber:   "შეიყვანეთ რიცხვი ან /cancel",
+		WizardUseButtons:    "დაადასტურეთ ან გააუქმეთ ზემოთ მოცემული ღილაკებით",
+		WizardInvalidDays:   "შეიყვანეთ დღეების მთელი დადებითი რაოდენობა",
+		WizardBudgetDays:    "რამდენ დღეზე იყოს ახალი ბიუჯეტი?",
+		WizardBudgetAmount:  "რა თანხის ბიუჯეტი? ხელმისაწვდომია (ბარათი + ნაღდი - რეზერვი): %s",
+		WizardBudgetReserve: "რამდენი დარჩეს რეზერვში? ახლა: %s",
+		WizardBudgetConfirm: "დაიწყოს %s ბიუჯეტი %s დღით, რეზერვით %s?",
+		WizardCashOnHand:    "რამდენი ნაღდი ფული გაქვთ? ჩაწერილია: %s",
+		WizardCardBalance:   "რა არის ბარათის ბალანსი? ჩაწერილია: %s",
+	},
+}
+
+// langNames are names of languages in themselves
+var langNames = map[Lang]string{
+	En: "English",
+	Ru: "Русский",
+	Ka: "ქართული",
+}
+
+// Name returns the language name in the language itself
+func (l Lang) Name() string {
+	return langNames[l]
+}
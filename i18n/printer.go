@@ -0,0 +1,144 @@
+package i18n
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Lang is a language of bot messages
+type Lang string
+
+// Supported languages
+const (
+	En Lang = "en"
+	Ru Lang = "ru"
+	Ka Lang = "ka"
+)
+
+// Langs are all supported languages in the order they are offered to users
+var Langs = []Lang{En, Ru, Ka}
+
+// ParseLang returns a supported language by its code
+func ParseLang(code string) (Lang, bool) {
+	for _, l := range Langs {
+		if string(l) == strings.ToLower(strings.TrimSpace(code)) {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// Printer formats messages, numbers and dates in a language
+type Printer struct {
+	lang Lang
+}
+
+// NewPrinter creates a printer of the given language, unsupported languages fall back to English
+func NewPrinter(lang Lang) Printer {
+	if _, ok := catalogue[lang]; !ok {
+		lang = En
+	}
+	return Printer{lang: lang}
+}
+
+// Lang returns the printer language
+func (p Printer) Lang() Lang {
+	return p.lang
+}
+
+// T returns the message of the given key formatted with args
+func (p Printer) T(key Key, args ...interface{}) string {
+	format, ok := catalogue[p.lang][key]
+	if !ok {
+		format = catalogue[En][key]
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// Number formats a value rounded to an integer with grouped thousands
+func (p Printer) Number(v float64) string {
+	return p.group(strconv.FormatInt(int64(math.Round(math.Abs(v))), 10), v < 0)
+}
+
+// SignedNumber formats a value like Number but with an explicit plus sign for positive values
+func (p Printer) SignedNumber(v float64) string {
+	s := p.Number(v)
+	if int64(math.Round(v)) > 0 {
+		s = "+" + s
+	}
+	return s
+}
+
+// Decimal formats a value with the given number of fraction digits
+func (p Printer) Decimal(v float64, prec int) string {
+	s := strconv.FormatFloat(math.Abs(v), 'f', prec, 64)
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+
+	res := p.group(intPart, v < 0 && s != strconv.FormatFloat(0, 'f', prec, 64))
+	if fracPart != "" {
+		res += formats[p.lang].decimalSep + fracPart
+	}
+	return res
+}
+
+// Date formats a date, e.g. "2 Jan 2006"
+func (p Printer) Date(t time.Time) string {
+	f := formats[p.lang]
+	return fmt.Sprintf("%d %s %d", t.Day(), f.months[t.Month()-1], t.Year())
+}
+
+// DateTime formats a date with time, e.g. "2 Jan 2006 15:04"
+func (p Printer) DateTime(t time.Time) string {
+	return p.Date(t) + " " + t.Format("15:04")
+}
+
+func (p Printer) group(digits string, negative bool) string {
+	sep := formats[p.lang].groupSep
+
+	var b strings.Builder
+	if negative {
+		b.WriteString("-")
+	}
+	for i, d := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(d)
+	}
+	return b.String()
+}
+
+type format struct {
+	groupSep   string
+	decimalSep string
+	months     [12]string
+}
+
+var formats = map[Lang]format{
+	En: {
+		groupSep:   ",",
+		decimalSep: ".",
+		months:     [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	},
+	Ru: {
+		groupSep:   "\u00a0",
+		decimalSep: ",",
+		months: [12]string{"января", "февраля", "марта", "апреля", "мая", "июня",
+			"июля", "августа", "сентября", "октября", "ноября", "декабря"},
+	},
+	Ka: {
+		groupSep:   "\u00a0",
+		decimalSep: ",",
+		months: [12]string{"იანვარი", "თებერვალი", "მარტი", "აპრილი", "მაისი", "ივნისი",
+			"ივლისი", "აგვისტო", "სექტემბერი", "ოქტომბერი", "ნოემბერი", "დეკემბერი"},
+	},
+}
@@ -0,0 +1,59 @@
+package i18n
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCatalogue(t *testing.T) {
+	for key := range catalogue[En] {
+		for _, lang := range Langs {
+			if _, ok := catalogue[lang][key]; !ok {
+				t.Errorf("%s: missing %s", lang, key)
+			}
+		}
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	tests := []struct {
+		lang     Lang
+		number   string
+		signed   string
+		decimal  string
+		negative string
+		date     string
+	}{
+		{En, "1,234,568", "+1,234,568", "1,234.50", "-0.5", "20 Nov 2023"},
+		{Ru, "1\u00a0234\u00a0568", "+1\u00a0234\u00a0568", "1\u00a0234,50", "-0,5", "20 ноября 2023"},
+		{Ka, "1\u00a0234\u00a0568", "+1\u00a0234\u00a0568", "1\u00a0234,50", "-0,5", "20 ნოემბერი 2023"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.lang), func(t *testing.T) {
+			p := NewPrinter(tt.lang)
+
+			if s := p.Number(1234567.5); s != tt.number {
+				t.Errorf("Number: %q", s)
+			}
+			if s := p.SignedNumber(1234567.5); s != tt.signed {
+				t.Errorf("SignedNumber: %q", s)
+			}
+			if s := p.Decimal(1234.5, 2); s != tt.decimal {
+				t.Errorf("Decimal: %q", s)
+			}
+			if s := p.Decimal(-0.5, 1); s != tt.negative {
+				t.Errorf("Decimal: %q", s)
+			}
+			if s := p.Date(time.Date(2023, 11, 20, 22, 30, 50, 0, time.UTC)); s != tt.date {
+				t.Errorf("Date: %q", s)
+			}
+		})
+	}
+
+	t.Run("fallback", func(t *testing.T) {
+		if p := NewPrinter("de"); p.Lang() != En {
+			t.Errorf("unexpected lang %s", p.Lang())
+		}
+	})
+}
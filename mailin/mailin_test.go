@@ -0,0 +1,116 @@
+package mailin
+
+import (
+	"context"
+	"net"
+	"net/smtp"
+	"strings"
+	"sync"
+	"testing"
+)
+
+const alertMail = "From: Bank <Alerts@Bank.ge>\r\n" +
+	"To: me@example.com\r\n" +
+	"Subject: =?UTF-8?B?0J7Qv9C10YDQsNGG0LjRjw==?=\r\n" +
+	"Date: Mon, 20 Nov 2023 10:15:00 +0400\r\n" +
+	"MIME-Version: 1.0\r\n" +
+	"Content-Type: multipart/alternative; boundary=b1\r\n" +
+	"\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/plain; charset=utf-8\r\n" +
+	"Content-Transfer-Encoding: quoted-printable\r\n" +
+	"\r\n" +
+	"12.50 GEL=0D=0A\r\n" +
+	"GOODWILL 20/11/2023 10:14:59=0D=0A\r\n" +
+	"Balance: 1000.00 GEL\r\n" +
+	"--b1\r\n" +
+	"Content-Type: text/html; charset=utf-8\r\n" +
+	"\r\n" +
+	"<p>12.50 GEL</p>\r\n" +
+	"--b1--\r\n"
+
+func TestParseMail(t *testing.T) {
+	m, err := ParseMail([]byte(alertMail))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if m.From != "alerts@bank.ge" || m.Subject != "Операция" || m.Date.Unix() != 1700460900 {
+		t.Errorf("unexpected mail %+v", m)
+	}
+	if want := "12.50 GEL\nGOODWILL 20/11/2023 10:14:59\nBalance: 1000.00 GEL"; m.Text != want {
+		t.Errorf("unexpected text %q", m.Text)
+	}
+
+	html := "From: alerts@bank.ge\r\nContent-Type: text/html\r\n\r\n<div>12.50&nbsp;GEL</div><div>Balance: 1000.00 GEL<br></div>"
+	m, err = ParseMail([]byte(html))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "12.50 GEL\nBalance: 1000.00 GEL"; m.Text != want {
+		t.Errorf("unexpected text of HTML %q", m.Text)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	allowed := Allowlist([]string{"alerts@bank.ge", "@cards.example.com"})
+
+	for from, want := range map[string]bool{
+		"Alerts@bank.ge":            true,
+		"noreply@cards.example.com": true,
+		"other@bank.ge":             false,
+		"alerts@bank.ge.evil.com":   false,
+	} {
+		if allowed(from) != want {
+			t.Errorf("allowed(%q) != %v", from, want)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var received []Mail
+	s := &Server{
+		Recipient: "alerts-secret@example.com",
+		Allowed:   Allowlist([]string{"alerts@bank.ge"}),
+		Handler: func(_ context.Context, m Mail) error {
+			mu.Lock()
+			defer mu.Unlock()
+			received = append(received, m)
+			return nil
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Serve(ctx, l) }()
+
+	if err := smtp.SendMail(l.Addr().String(), nil, "alerts@bank.ge", []string{"Alerts-Secret@example.com"}, []byte(alertMail)); err != nil {
+		t.Fatal(err)
+	}
+
+	err = smtp.SendMail(l.Addr().String(), nil, "alerts@bank.ge", []string{"me@example.com"}, []byte(alertMail))
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("unexpected error of a wrong recipient: %v", err)
+	}
+
+	spoofed := strings.Replace(alertMail, "Alerts@Bank.ge", "alerts@evil.com", 1)
+	err = smtp.SendMail(l.Addr().String(), nil, "alerts@bank.ge", []string{"alerts-secret@example.com"}, []byte(spoofed))
+	if err == nil || !strings.Contains(err.Error(), "550") {
+		t.Errorf("unexpected error of a not allowed sender: %v", err)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(received) != 1 || received[0].From != "alerts@bank.ge" {
+		t.Errorf("unexpected received mail %+v", received)
+	}
+}
@@ -0,0 +1,155 @@
+package mailin
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// maxParts limits a number of MIME parts walked in a message
+const maxParts = 32
+
+// Mail is a received email
+type Mail struct {
+	// From is the lowercased address of the From header
+	From    string
+	Subject string
+	// Text is the plain text of the message, taken from the HTML part if there is no plain text part
+	Text string
+	// Date is the Date header or the receiving time
+	Date time.Time
+}
+
+var (
+	tagRE        = regexp.MustCompile(`(?s)<(script|style)[^>]*>.*?</(script|style)>|<[^>]+>`)
+	lineBreakRE  = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</tr>`)
+	blankLinesRE = regexp.MustCompile(`\n[ \t]*(\n[ \t]*)+`)
+)
+
+// ParseMail parses a raw message
+func ParseMail(data []byte) (Mail, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return Mail{}, fmt.Errorf("mail.ReadMessage: %w", err)
+	}
+
+	from, err := mail.ParseAddress(msg.Header.Get("From"))
+	if err != nil {
+		return Mail{}, fmt.Errorf("invalid From: %w", err)
+	}
+
+	dec := new(mime.WordDecoder)
+	subject, err := dec.DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil {
+		subject = msg.Header.Get("Subject")
+	}
+
+	date, err := msg.Header.Date()
+	if err != nil {
+		date = time.Now()
+	}
+
+	parts := 0
+	plain, htmlText, err := extractText(msg.Header.Get("Content-Type"), msg.Header.Get("Content-Transfer-Encoding"), msg.Body, &parts)
+	if err != nil {
+		return Mail{}, err
+	}
+
+	text := plain
+	if strings.TrimSpace(text) == "" {
+		text = htmlToText(htmlText)
+	}
+
+	return Mail{
+		From:    strings.ToLower(from.Address),
+		Subject: subject,
+		Text:    normalize(text),
+		Date:    date,
+	}, nil
+}
+
+// extractText returns the first text/plain and text/html parts of the entity
+func extractText(contentType, encoding string, body io.Reader, parts *int) (string, string, error) {
+	*parts++
+	if *parts > maxParts {
+		return "", "", errors.New("too many MIME parts")
+	}
+
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		var plain, htmlText string
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			p, err := r.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", "", fmt.Errorf("multipart: %w", err)
+			}
+			pt, ph, err := extractText(p.Header.Get("Content-Type"), p.Header.Get("Content-Transfer-Encoding"), p, parts)
+			if err != nil {
+				return "", "", err
+			}
+			if plain == "" {
+				plain = pt
+			}
+			if htmlText == "" {
+				htmlText = ph
+			}
+		}
+		return plain, htmlText, nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", "", nil
+	}
+
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		body = quotedprintable.NewReader(body)
+	case "base64":
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", "", fmt.Errorf("decode %s: %w", encoding, err)
+	}
+
+	if mediaType == "text/html" {
+		return "", string(data), nil
+	}
+	return string(data), "", nil
+}
+
+func htmlToText(s string) string {
+	s = lineBreakRE.ReplaceAllString(s, "\n")
+	s = tagRE.ReplaceAllString(s, "")
+	return html.UnescapeString(s)
+}
+
+func normalize(s string) string {
+	s = strings.ReplaceAll(s, "\r\n", "\n")
+	s = strings.ReplaceAll(s, "\u00a0", " ")
+	s = blankLinesRE.ReplaceAllString(s, "\n")
+
+	lines := strings.Split(strings.TrimSpace(s), "\n")
+	for i := range lines {
+		lines[i] = strings.TrimSpace(lines[i])
+	}
+	return strings.Join(lines, "\n")
+}
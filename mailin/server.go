@@ -0,0 +1,254 @@
+// Package mailin is a minimal SMTP receiver of bank notification emails.
+package mailin
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultMaxSize limits a size of a message
+	DefaultMaxSize = 1 << 20
+	// maxRecipients limits recipients of a message
+	maxRecipients = 16
+	// sessionTimeout limits a whole SMTP session
+	sessionTimeout = time.Minute * 5
+	// maxConnections limits concurrent sessions
+	maxConnections = 16
+)
+
+// Handler processes an accepted message, an error rejects the message
+type Handler func(ctx context.Context, m Mail) error
+
+// Server receives emails from allowed senders to the secret recipient
+type Server struct {
+	// Hostname is announced in the greeting
+	Hostname string
+	// Recipient is the only accepted RCPT address, it is the secret of the server since From can be forged,
+	// all messages are rejected if it is empty
+	Recipient string
+	// Allowed reports whether a From address is allowed
+	Allowed func(from string) bool
+	Handler Handler
+	// MaxSize limits a message size, DefaultMaxSize if 0
+	MaxSize int
+
+	sem chan struct{}
+	wg  sync.WaitGroup
+}
+
+// Allowlist returns a sender check accepting the listed addresses and domains written as "@domain"
+func Allowlist(senders []string) func(from string) bool {
+	allowed := map[string]bool{}
+	for _, s := range senders {
+		if s = strings.ToLower(strings.TrimSpace(s)); s != "" {
+			allowed[s] = true
+		}
+	}
+
+	return func(from string) bool {
+		from = strings.ToLower(from)
+		if allowed[from] {
+			return true
+		}
+		at := strings.LastIndex(from, "@")
+		return at >= 0 && allowed[from[at:]]
+	}
+}
+
+// ListenAndServe serves SMTP on the address until ctx is done
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("net.Listen: %w", err)
+	}
+
+	return s.Serve(ctx, l)
+}
+
+// Serve serves SMTP on the listener until ctx is done
+func (s *Server) Serve(ctx context.Context, l net.Listener) error {
+	s.sem = make(chan struct{}, maxConnections)
+	go func() {
+		<-ctx.Done()
+		l.Close()
+	}()
+
+	defer s.wg.Wait()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() {
+				continue
+			}
+			return err
+		}
+
+		select {
+		case s.sem <- struct{}{}:
+		default:
+			_, _ = io.WriteString(conn, "421 4.3.2 too many connections\r\n")
+			conn.Close()
+			continue
+		}
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer func() { <-s.sem }()
+			s.serveConn(ctx, conn)
+		}()
+	}
+}
+
+// session is a state of an SMTP transaction
+type session struct {
+	from       string
+	recipients int
+}
+
+func (s *Server) serveConn(ctx context.Context, conn net.Conn) {
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(sessionTimeout))
+
+	tp := textproto.NewConn(conn)
+	reply := func(code int, msg string) {
+		_ = tp.PrintfLine("%d %s", code, msg)
+	}
+
+	hostname := s.Hostname
+	if hostname == "" {
+		hostname = "localhost"
+	}
+	reply(220, hostname+" ESMTP alfabooker")
+
+	var sess session
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+
+		verb, arg := line, ""
+		if i := strings.IndexByte(line, ' '); i >= 0 {
+			verb, arg = line[:i], strings.TrimSpace(line[i+1:])
+		}
+
+		switch strings.ToUpper(verb) {
+		case "HELO":
+			reply(250, hostname)
+		case "EHLO":
+			_ = tp.PrintfLine("250-%s", hostname)
+			_ = tp.PrintfLine("250-8BITMIME")
+			_ = tp.PrintfLine("250 SIZE %d", s.maxSize())
+		case "MAIL":
+			if !strings.HasPrefix(strings.ToUpper(arg), "FROM:") {
+				reply(501, "5.5.4 syntax: MAIL FROM:<address>")
+				continue
+			}
+			sess = session{from: strings.TrimSpace(arg[5:])}
+			reply(250, "2.1.0 ok")
+		case "RCPT":
+			if sess.from == "" {
+				reply(503, "5.5.1 MAIL first")
+				continue
+			}
+			if sess.recipients >= maxRecipients {
+				reply(452, "4.5.3 too many recipients")
+				continue
+			}
+			if !strings.HasPrefix(strings.ToUpper(arg), "TO:") {
+				reply(501, "5.5.4 syntax: RCPT TO:<address>")
+				continue
+			}
+			if !s.isRecipient(arg[3:]) {
+				log.Println("mailin: rejected a recipient", arg[3:])
+				reply(550, "5.1.1 no such user")
+				continue
+			}
+			sess.recipients++
+			reply(250, "2.1.5 ok")
+		case "DATA":
+			if sess.recipients == 0 {
+				reply(503, "5.5.1 RCPT first")
+				continue
+			}
+			reply(354, "end data with <CR><LF>.<CR><LF>")
+			code, msg := s.receive(ctx, tp.R)
+			reply(code, msg)
+			sess = session{}
+		case "RSET":
+			sess = session{}
+			reply(250, "2.0.0 ok")
+		case "NOOP":
+			reply(250, "2.0.0 ok")
+		case "QUIT":
+			reply(221, "2.0.0 bye")
+			return
+		default:
+			reply(502, "5.5.2 command not implemented")
+		}
+	}
+}
+
+// receive reads the message data and returns the reply
+func (s *Server) receive(ctx context.Context, r *bufio.Reader) (int, string) {
+	dot := textproto.NewReader(r).DotReader()
+	data, err := io.ReadAll(io.LimitReader(dot, int64(s.maxSize())+1))
+	if err != nil {
+		return 451, "4.3.0 read error"
+	}
+	if len(data) > s.maxSize() {
+		_, _ = io.Copy(io.Discard, dot)
+		return 552, "5.3.4 message is too large"
+	}
+
+	m, err := ParseMail(data)
+	if err != nil {
+		log.Println("mailin.ParseMail:", err)
+		return 554, "5.6.0 malformed message"
+	}
+
+	if s.Allowed == nil || !s.Allowed(m.From) {
+		log.Println("mailin: rejected a message from", m.From)
+		return 550, "5.7.1 sender is not allowed"
+	}
+
+	if err := s.Handler(ctx, m); err != nil {
+		log.Println("mailin.Handler:", err)
+		return 554, "5.6.0 message is not recognised"
+	}
+
+	return 250, "2.0.0 accepted"
+}
+
+// isRecipient reports whether a RCPT TO path like "<me@example.com> NOTIFY=NEVER" is the secret recipient
+func (s *Server) isRecipient(path string) bool {
+	path = strings.TrimSpace(path)
+	if i := strings.IndexByte(path, '>'); i >= 0 {
+		path = path[:i]
+	}
+	path = strings.TrimPrefix(path, "<")
+
+	return s.Recipient != "" && strings.EqualFold(path, s.Recipient)
+}
+
+func (s *Server) maxSize() int {
+	if s.MaxSize > 0 {
+		return s.MaxSize
+	}
+	return DefaultMaxSize
+}
@@ -0,0 +1,348 @@
+// Package metrics exposes counters, gauges and histograms in the Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are histogram buckets suitable for request latencies in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry metrics of the app are registered in
+var Default = NewRegistry()
+
+type metric interface {
+	write(w io.Writer) error
+}
+
+// Registry is a set of metrics exposed together
+type Registry struct {
+	mu      sync.Mutex
+	metrics []metric
+	hooks   []func(ctx context.Context)
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+// OnCollect registers a hook called before metrics are written, e.g. to set gauges of the current state
+func (r *Registry) OnCollect(hook func(ctx context.Context)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.hooks = append(r.hooks, hook)
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.metrics = append(r.metrics, m)
+}
+
+// Write runs collect hooks and writes all metrics in the text exposition format
+func (r *Registry) Write(ctx context.Context, w io.Writer) error {
+	r.mu.Lock()
+	hooks := r.hooks
+	metrics := r.metrics
+	r.mu.Unlock()
+
+	for _, hook := range hooks {
+		hook(ctx)
+	}
+
+	for _, m := range metrics {
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Handler returns a handler serving the metrics
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = r.Write(request.Context(), writer)
+	})
+}
+
+// desc describes a metric family
+type desc struct {
+	name   string
+	help   string
+	typ    string
+	labels []string
+}
+
+func (d desc) writeHeader(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.typ)
+	return err
+}
+
+// series are values of a metric family by label values
+type series struct {
+	mu     sync.Mutex
+	values map[string]*value
+}
+
+type value struct {
+	labels []string
+	v      float64
+	// buckets and count are used by histograms
+	buckets []uint64
+	count   uint64
+}
+
+func (s *series) get(d desc, labelValues []string) *value {
+	if len(labelValues) != len(d.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", d.name, len(d.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	if s.values == nil {
+		s.values = map[string]*value{}
+	}
+	v, ok := s.values[key]
+	if !ok {
+		v = &value{labels: append([]string(nil), labelValues...)}
+		s.values[key] = v
+	}
+	return v
+}
+
+// sorted returns copies of values sorted by label values
+func (s *series) sorted() []value {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	res := make([]value, 0, len(keys))
+	for _, k := range keys {
+		v := *s.values[k]
+		v.buckets = append([]uint64(nil), v.buckets...)
+		res = append(res, v)
+	}
+	return res
+}
+
+// Counter is a monotonically increasing value
+type Counter struct {
+	desc
+	series
+}
+
+// NewCounter registers a counter in the Default registry
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+// NewCounter registers a counter
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{desc: desc{name: name, help: help, typ: "counter", labels: labels}}
+	r.register(c)
+	return c
+}
+
+// Inc increments the counter of the label values
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds a non-negative delta to the counter of the label values
+func (c *Counter) Add(delta float64, labelValues ...string) {
+	if delta < 0 {
+		panic("metrics: counter can not decrease")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.get(c.desc, labelValues).v += delta
+}
+
+func (c *Counter) write(w io.Writer) error {
+	return writeValues(w, c.desc, c.sorted())
+}
+
+// Gauge is a value which can go up and down
+type Gauge struct {
+	desc
+	series
+}
+
+// NewGauge registers a gauge in the Default registry
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+// NewGauge registers a gauge
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{desc: desc{name: name, help: help, typ: "gauge", labels: labels}}
+	r.register(g)
+	return g
+}
+
+// Set sets the gauge of the label values
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.get(g.desc, labelValues).v = v
+}
+
+func (g *Gauge) write(w io.Writer) error {
+	return writeValues(w, g.desc, g.sorted())
+}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	desc
+	series
+	bounds []float64
+	// leLabels are label names of buckets
+	leLabels []string
+}
+
+// NewHistogram registers a histogram in the Default registry
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+// NewHistogram registers a histogram with the given bucket upper bounds
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	h := &Histogram{
+		desc:     desc{name: name, help: help, typ: "histogram", labels: labels},
+		bounds:   buckets,
+		leLabels: withLabel(labels, "le"),
+	}
+	r.register(h)
+	return h
+}
+
+// Observe adds an observation of the label values
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	val := h.get(h.desc, labelValues)
+	if val.buckets == nil {
+		val.buckets = make([]uint64, len(h.bounds))
+	}
+	for i, bound := range h.bounds {
+		if v <= bound {
+			val.buckets[i]++
+		}
+	}
+	val.v += v
+	val.count++
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	values := h.sorted()
+	if len(values) == 0 {
+		return nil
+	}
+
+	if err := h.writeHeader(w); err != nil {
+		return err
+	}
+
+	for _, v := range values {
+		for i, bound := range h.bounds {
+			le := formatLabels(h.leLabels, withLabel(v.labels, formatFloat(bound)))
+			if _, err := fmt.Fprintf(w, "%s_bucket%s %d\n", h.name, le, v.buckets[i]); err != nil {
+				return err
+			}
+		}
+
+		labels := formatLabels(h.labels, v.labels)
+		inf := formatLabels(h.leLabels, withLabel(v.labels, "+Inf"))
+		_, err := fmt.Fprintf(w, "%s_bucket%s %d\n%s_sum%s %s\n%s_count%s %d\n",
+			h.name, inf, v.count,
+			h.name, labels, formatFloat(v.v),
+			h.name, labels, v.count,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func writeValues(w io.Writer, d desc, values []value) error {
+	if len(values) == 0 {
+		return nil
+	}
+
+	if err := d.writeHeader(w); err != nil {
+		return err
+	}
+
+	for _, v := range values {
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", d.name, formatLabels(d.labels, v.labels), formatFloat(v.v)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// withLabel returns a copy of labels with one more appended
+func withLabel(labels []string, label string) []string {
+	res := make([]string, 0, len(labels)+1)
+	return append(append(res, labels...), label)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + `="` + escapeLabel(values[i]) + `"`
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
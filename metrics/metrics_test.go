@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"testing"
+)
+
+func TestRegistryWrite(t *testing.T) {
+	r := NewRegistry()
+
+	c := r.NewCounter("test_total", "Test counter", "result")
+	c.Inc("ok")
+	c.Add(2, `bad "quoted"`)
+	c.Inc("ok")
+
+	g := r.NewGauge("test_gauge", "Test gauge")
+	r.OnCollect(func(context.Context) {
+		g.Set(-1.5)
+	})
+
+	h := r.NewHistogram("test_seconds", "Test histogram", []float64{0.1, 1}, "route")
+	h.Observe(0.05, "/a")
+	h.Observe(0.5, "/a")
+
+	r.NewCounter("unused_total", "Never incremented")
+
+	var buf bytes.Buffer
+	if err := r.Write(context.Background(), &buf); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `# HELP test_total Test counter
+# TYPE test_total counter
+test_total{result="bad \"quoted\""} 2
+test_total{result="ok"} 2
+# HELP test_gauge Test gauge
+# TYPE test_gauge gauge
+test_gauge -1.5
+# HELP test_seconds Test histogram
+# TYPE test_seconds histogram
+test_seconds_bucket{route="/a",le="0.1"} 1
+test_seconds_bucket{route="/a",le="1"} 2
+test_seconds_bucket{route="/a",le="+Inf"} 2
+test_seconds_sum{route="/a"} 0.55
+test_seconds_count{route="/a"} 2
+`
+	if buf.String() != want {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,184 @@
+package mqtt
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Control packet types of MQTT 3.1.1
+const (
+	packetConnect     = 1
+	packetConnack     = 2
+	packetPublish     = 3
+	packetPuback      = 4
+	packetSubscribe   = 8
+	packetSuback      = 9
+	packetPingreq     = 12
+	packetPingresp    = 13
+	packetDisconnect  = 14
+	protocolLevel311  = 4
+	maxRemainingBytes = 268435455
+)
+
+// Connect flags
+const (
+	flagCleanSession = 0x02
+	flagWill         = 0x04
+	flagWillRetain   = 0x20
+	flagPassword     = 0x40
+	flagUsername     = 0x80
+)
+
+// packet is a raw control packet
+type packet struct {
+	kind  byte
+	flags byte
+	body  []byte
+}
+
+func writePacket(w io.Writer, p packet) error {
+	if len(p.body) > maxRemainingBytes {
+		return fmt.Errorf("packet of %d bytes is too large", len(p.body))
+	}
+
+	header := []byte{p.kind<<4 | p.flags}
+	n := len(p.body)
+	for {
+		b := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			b |= 0x80
+		}
+		header = append(header, b)
+		if n == 0 {
+			break
+		}
+	}
+
+	if _, err := w.Write(append(header, p.body...)); err != nil {
+		return err
+	}
+	return nil
+}
+
+func readPacket(r *bufio.Reader) (packet, error) {
+	first, err := r.ReadByte()
+	if err != nil {
+		return packet{}, err
+	}
+
+	length, multiplier := 0, 1
+	for i := 0; ; i++ {
+		if i == 4 {
+			return packet{}, errors.New("malformed remaining length")
+		}
+		b, err := r.ReadByte()
+		if err != nil {
+			return packet{}, err
+		}
+		length += int(b&0x7f) * multiplier
+		multiplier *= 128
+		if b&0x80 == 0 {
+			break
+		}
+	}
+
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return packet{}, err
+	}
+
+	return packet{kind: first >> 4, flags: first & 0x0f, body: body}, nil
+}
+
+func appendString(b []byte, s string) []byte {
+	return appendBytes(b, []byte(s))
+}
+
+func appendBytes(b []byte, data []byte) []byte {
+	b = binary.BigEndian.AppendUint16(b, uint16(len(data)))
+	return append(b, data...)
+}
+
+func readString(b []byte) (string, []byte, error) {
+	if len(b) < 2 {
+		return "", nil, errors.New("malformed string")
+	}
+	n := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+n {
+		return "", nil, errors.New("malformed string")
+	}
+	return string(b[2 : 2+n]), b[2+n:], nil
+}
+
+func connectPacket(opts Options) packet {
+	var flags byte = flagCleanSession
+	if opts.Will != nil {
+		flags |= flagWill
+		if opts.Will.Retain {
+			flags |= flagWillRetain
+		}
+	}
+	if opts.Username != "" {
+		flags |= flagUsername
+	}
+	if opts.Password != "" {
+		flags |= flagPassword
+	}
+
+	body := appendString(nil, "MQTT")
+	body = append(body, protocolLevel311, flags)
+	body = binary.BigEndian.AppendUint16(body, uint16(opts.KeepAlive.Seconds()))
+	body = appendString(body, opts.ClientID)
+	if opts.Will != nil {
+		body = appendString(body, opts.Will.Topic)
+		body = appendBytes(body, opts.Will.Payload)
+	}
+	if opts.Username != "" {
+		body = appendString(body, opts.Username)
+	}
+	if opts.Password != "" {
+		body = appendString(body, opts.Password)
+	}
+
+	return packet{kind: packetConnect, body: body}
+}
+
+// publishPacket is a QoS 0 publish
+func publishPacket(m Message) packet {
+	var flags byte
+	if m.Retain {
+		flags = 0x01
+	}
+	return packet{kind: packetPublish, flags: flags, body: append(appendString(nil, m.Topic), m.Payload...)}
+}
+
+// parsePublish parses a publish packet, packetID is 0 for QoS 0
+func parsePublish(p packet) (Message, uint16, error) {
+	topic, rest, err := readString(p.body)
+	if err != nil {
+		return Message{}, 0, err
+	}
+
+	var packetID uint16
+	if qos := (p.flags >> 1) & 0x03; qos > 0 {
+		if len(rest) < 2 {
+			return Message{}, 0, errors.New("malformed publish")
+		}
+		packetID = binary.BigEndian.Uint16(rest)
+		rest = rest[2:]
+	}
+
+	return Message{Topic: topic, Payload: rest, Retain: p.flags&0x01 != 0}, packetID, nil
+}
+
+// subscribePacket subscribes to the topic filter with QoS 0
+func subscribePacket(packetID uint16, filter string) packet {
+	body := binary.BigEndian.AppendUint16(nil, packetID)
+	body = appendString(body, filter)
+	body = append(body, 0)
+	return packet{kind: packetSubscribe, flags: 0x02, body: body}
+}
@@ -0,0 +1,149 @@
+package statement
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// csvColumns are header names of statement columns, a header matches if it contains a name
+var csvColumns = map[string][]string{
+	"date":     {"date", "time", "дата", "თარიღ"},
+	"amount":   {"amount", "сумма", "თანხა"},
+	"debit":    {"debit", "дебет", "დებეტ"},
+	"credit":   {"credit", "кредит", "კრედიტ"},
+	"currency": {"currency", "валюта", "ვალუტა"},
+	"merchant": {"merchant", "payee", "description", "details", "описание", "назначение", "დანიშნულება", "აღწერა"},
+	"id":       {"id", "reference", "ref", "номер", "ნომერი"},
+}
+
+// columnOrder resolves headers matching several columns, "transaction id" is an ID rather than a date
+var columnOrder = []string{"id", "currency", "debit", "credit", "amount", "date", "merchant"}
+
+func parseCSV(data []byte) ([]budget.StatementRow, error) {
+	data = bytes.TrimPrefix(data, []byte("\ufeff"))
+
+	firstLine := data
+	if i := bytes.IndexByte(data, '\n'); i >= 0 {
+		firstLine = data[:i]
+	}
+	comma := ','
+	for _, c := range []rune{';', '\t'} {
+		if bytes.Count(firstLine, []byte(string(c))) > bytes.Count(firstLine, []byte(string(comma))) {
+			comma = c
+		}
+	}
+
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = comma
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("csv.ReadAll: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty statement")
+	}
+
+	cols := map[string]int{}
+	for i, header := range records[0] {
+		header = strings.ToLower(strings.TrimSpace(header))
+		for _, col := range columnOrder {
+			if _, ok := cols[col]; ok || !matchesAny(header, csvColumns[col]) {
+				continue
+			}
+			cols[col] = i
+			break
+		}
+	}
+
+	if _, ok := cols["date"]; !ok {
+		return nil, fmt.Errorf("no date column in %v", records[0])
+	}
+	_, hasAmount := cols["amount"]
+	_, hasDebit := cols["debit"]
+	if !hasAmount && !hasDebit {
+		return nil, fmt.Errorf("no amount column in %v", records[0])
+	}
+
+	rows := make([]budget.StatementRow, 0, len(records)-1)
+	for n, rec := range records[1:] {
+		field := func(col string) string {
+			i, ok := cols[col]
+			if !ok || i >= len(rec) {
+				return ""
+			}
+			return strings.TrimSpace(rec[i])
+		}
+
+		// summary lines at the end of statements have no date
+		if field("date") == "" {
+			continue
+		}
+
+		row, err := parseCSVRow(field)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n+2, err)
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+func parseCSVRow(field func(col string) string) (budget.StatementRow, error) {
+	row := budget.StatementRow{
+		ID:       field("id"),
+		Currency: strings.ToUpper(field("currency")),
+		Merchant: field("merchant"),
+	}
+
+	at, err := parseDate(field("date"))
+	if err != nil {
+		return row, err
+	}
+	row.At = at.Unix()
+
+	if s := field("amount"); s != "" {
+		if row.Amount, err = parseAmount(s); err != nil {
+			return row, err
+		}
+		return row, nil
+	}
+
+	if s := field("credit"); s != "" {
+		if row.Amount, err = parseAmount(s); err != nil {
+			return row, err
+		}
+	}
+	if s := field("debit"); s != "" {
+		debit, err := parseAmount(s)
+		if err != nil {
+			return row, err
+		}
+		row.Amount -= debit
+	}
+
+	return row, nil
+}
+
+func matchesAny(header string, names []string) bool {
+	for _, name := range names {
+		if name == "id" || name == "ref" {
+			// short names match whole words only, "paid" is not an ID
+			for _, w := range strings.FieldsFunc(header, func(r rune) bool { return r == ' ' || r == '_' || r == '.' }) {
+				if w == name {
+					return true
+				}
+			}
+			continue
+		}
+		if strings.Contains(header, name) {
+			return true
+		}
+	}
+	return false
+}
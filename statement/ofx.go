@@ -0,0 +1,93 @@
+package statement
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// ofxTransactionRE matches transactions of both SGML (OFX 1.x) and XML (OFX 2.x) statements
+var ofxTransactionRE = regexp.MustCompile(`(?is)<STMTTRN>(.*?)</STMTTRN>`)
+
+// ofxFieldRE matches an element value, closing tags are optional in SGML
+var ofxFieldRE = regexp.MustCompile(`(?i)<([A-Z0-9.]+)>([^<\r\n]*)`)
+
+// ofxCurrencyRE matches the statement currency
+var ofxCurrencyRE = regexp.MustCompile(`(?i)<CURDEF>\s*([A-Z]{3})`)
+
+func parseOFX(data []byte) ([]budget.StatementRow, error) {
+	currency := ""
+	if m := ofxCurrencyRE.FindSubmatch(data); m != nil {
+		currency = strings.ToUpper(string(m[1]))
+	}
+
+	var rows []budget.StatementRow
+	for n, m := range ofxTransactionRE.FindAllSubmatch(data, -1) {
+		fields := map[string]string{}
+		for _, f := range ofxFieldRE.FindAllStringSubmatch(string(m[1]), -1) {
+			fields[strings.ToUpper(f[1])] = html.UnescapeString(strings.TrimSpace(f[2]))
+		}
+
+		at, err := parseOFXTime(fields["DTPOSTED"])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", n+1, err)
+		}
+
+		amount, err := parseAmount(fields["TRNAMT"])
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", n+1, err)
+		}
+
+		merchant := fields["NAME"]
+		if merchant == "" {
+			merchant = fields["MEMO"]
+		}
+
+		rows = append(rows, budget.StatementRow{
+			ID:       fields["FITID"],
+			At:       at.Unix(),
+			Amount:   amount,
+			Currency: currency,
+			Merchant: merchant,
+		})
+	}
+
+	if rows == nil && !strings.Contains(strings.ToUpper(string(data)), "<OFX>") {
+		return nil, fmt.Errorf("not an OFX document")
+	}
+
+	return rows, nil
+}
+
+// parseOFXTime parses OFX dates like "20231120", "20231120223050" or "20231120223050.000[+4:GET]"
+func parseOFXTime(s string) (time.Time, error) {
+	offset := 0
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		tz := strings.TrimSuffix(s[i+1:], "]")
+		if j := strings.IndexByte(tz, ':'); j >= 0 {
+			tz = tz[:j]
+		}
+		fmt.Sscanf(tz, "%d", &offset)
+		s = s[:i]
+	}
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+
+	layouts := map[int]string{8: "20060102", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[len(s)]
+	if !ok {
+		return time.Time{}, fmt.Errorf("invalid OFX date: %q", s)
+	}
+
+	t, err := time.ParseInLocation(layout, s, time.FixedZone("", offset*3600))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid OFX date: %q", s)
+	}
+
+	return t, nil
+}
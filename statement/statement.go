@@ -0,0 +1,120 @@
+// Package statement parses bank statement files.
+package statement
+
+import (
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// Format is a statement file format
+type Format string
+
+// Statement formats
+const (
+	FormatCSV Format = "csv"
+	FormatOFX Format = "ofx"
+)
+
+// DetectFormat detects a statement format by the file name or content
+func DetectFormat(name string, data []byte) (Format, bool) {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".csv":
+		return FormatCSV, true
+	case ".ofx", ".qfx":
+		return FormatOFX, true
+	}
+
+	head := bytes.ToUpper(data[:min(len(data), 512)])
+	if bytes.Contains(head, []byte("OFXHEADER")) || bytes.Contains(head, []byte("<OFX>")) {
+		return FormatOFX, true
+	}
+	if bytes.ContainsAny(head, ",;\t") {
+		return FormatCSV, true
+	}
+
+	return "", false
+}
+
+// ParseFormat parses a format name
+func ParseFormat(s string) (Format, bool) {
+	switch Format(strings.ToLower(s)) {
+	case FormatCSV:
+		return FormatCSV, true
+	case FormatOFX, "qfx":
+		return FormatOFX, true
+	}
+	return "", false
+}
+
+// Parse parses statement rows
+func Parse(f Format, data []byte) ([]budget.StatementRow, error) {
+	switch f {
+	case FormatCSV:
+		return parseCSV(data)
+	case FormatOFX:
+		return parseOFX(data)
+	}
+
+	return nil, fmt.Errorf("unknown format: %s", f)
+}
+
+// dateLayouts are date formats seen in bank statements
+var dateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"02/01/2006 15:04:05",
+	"02/01/2006 15:04",
+	"02/01/2006",
+	"02.01.2006 15:04:05",
+	"02.01.2006 15:04",
+	"02.01.2006",
+}
+
+// parseDate parses a date in UTC
+func parseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("unknown date format: %q", s)
+}
+
+// parseAmount parses amounts like "-1,234.56", "1 234,56" or "12.50"
+func parseAmount(s string) (float64, error) {
+	s = strings.Map(func(r rune) rune {
+		if r == ' ' || r == '\u00a0' || r == '\'' {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(s))
+
+	dot, comma := strings.LastIndex(s, "."), strings.LastIndex(s, ",")
+	switch {
+	case dot >= 0 && comma >= 0 && comma > dot:
+		s = strings.ReplaceAll(s, ".", "")
+		s = strings.Replace(s, ",", ".", 1)
+	case dot >= 0 && comma >= 0:
+		s = strings.ReplaceAll(s, ",", "")
+	case comma >= 0 && len(s)-comma-1 == 2:
+		// a single comma with two digits after it is a decimal separator
+		s = strings.Replace(s, ",", ".", 1)
+	case comma >= 0:
+		s = strings.ReplaceAll(s, ",", "")
+	}
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount: %q", s)
+	}
+	return v, nil
+}
@@ -0,0 +1,90 @@
+package statement
+
+import (
+	"testing"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+func TestParseAmount(t *testing.T) {
+	tests := map[string]float64{
+		"12.50":     12.5,
+		"-1,234.56": -1234.56,
+		"1 234,56":  1234.56,
+		"1.234,56":  1234.56,
+		"-12,50":    -12.5,
+		"1,234":     1234,
+		"1 000":     1000,
+	}
+
+	for s, want := range tests {
+		got, err := parseAmount(s)
+		if err != nil || got != want {
+			t.Errorf("parseAmount(%q) = %v, %v", s, got, err)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want []budget.StatementRow
+	}{
+		{
+			name: "csv",
+			data: "Date;Description;Amount;Currency\n20.11.2023;LTD MP DEVELOPMENT;-1,00;GEL\n21.11.2023;Salary;100,00;gel\n;Total;99,00;\n",
+			want: []budget.StatementRow{
+				{At: 1700438400, Amount: -1, Currency: "GEL", Merchant: "LTD MP DEVELOPMENT"},
+				{At: 1700524800, Amount: 100, Currency: "GEL", Merchant: "Salary"},
+			},
+		},
+		{
+			name: "csv debit credit",
+			data: "Transaction ID,Posting date,Details,Debit,Credit\nA1,2023-11-20,Coffee,4.50,\nA2,2023-11-21,Refund,,4.50\n",
+			want: []budget.StatementRow{
+				{ID: "A1", At: 1700438400, Amount: -4.5, Merchant: "Coffee"},
+				{ID: "A2", At: 1700524800, Amount: 4.5, Merchant: "Refund"},
+			},
+		},
+		{
+			name: "ofx sgml",
+			data: `OFXHEADER:100
+DATA:OFXSGML
+<OFX><BANKMSGSRSV1><STMTTRNRS><STMTRS><CURDEF>GEL
+<BANKTRANLIST>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20231120223050.000[+4:GET]<TRNAMT>-1.00<FITID>F1<NAME>LTD MP DEVELOPMENT
+</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20231121<TRNAMT>100.00<FITID>F2<MEMO>Salary &amp; bonus
+</STMTTRN>
+</BANKTRANLIST></STMTRS></STMTTRNRS></BANKMSGSRSV1></OFX>`,
+			want: []budget.StatementRow{
+				{ID: "F1", At: 1700505050, Amount: -1, Currency: "GEL", Merchant: "LTD MP DEVELOPMENT"},
+				{ID: "F2", At: 1700524800, Amount: 100, Currency: "GEL", Merchant: "Salary & bonus"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, ok := DetectFormat("", []byte(tt.data))
+			if !ok {
+				t.Fatal("format is not detected")
+			}
+
+			rows, err := Parse(f, []byte(tt.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(rows) != len(tt.want) {
+				t.Fatalf("unexpected rows %+v", rows)
+			}
+			for i := range rows {
+				if rows[i] != tt.want[i] {
+					t.Errorf("row %d: %+v, want %+v", i, rows[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -1,40 +1,102 @@
 package tg
 
 import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strings"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 )
 
+// secretTokenHeader is a header Telegram puts the webhook secret token into
+const secretTokenHeader = "X-Telegram-Bot-Api-Secret-Token"
+
+// allowedUpdates are update types the bot subscribes to
+var allowedUpdates = []string{"message", "edited_message", "callback_query"}
+
+// UpdatesLog remembers handled updates
+type UpdatesLog interface {
+	// MarkHandled marks the update as handled and reports whether it has been already handled before
+	MarkHandled(ctx context.Context, updateID int) (bool, error)
+}
+
+// BotConfig is a bot configuration
+type BotConfig struct {
+	Token string
+	// APIEndpoint is a Bot API endpoint format, tgbotapi.APIEndpoint if empty
+	APIEndpoint string
+	// WebhookSecret is a secret Telegram sends with every webhook request, derived from Token if empty
+	WebhookSecret string
+	// UpdatesLog is used to skip redelivered updates, nil disables de-duplication
+	UpdatesLog UpdatesLog
+}
+
 // GetBot creates a telegram API instance
-func GetBot(botToken string, h func(UserMsg)) (*Bot, error) {
-	bot, err := tgbotapi.NewBotAPI(botToken)
+func GetBot(cfg BotConfig, h func(UserMsg), btnH func(BtnClick)) (*Bot, error) {
+	endpoint := cfg.APIEndpoint
+	if endpoint == "" {
+		endpoint = tgbotapi.APIEndpoint
+	}
+
+	bot, err := tgbotapi.NewBotAPIWithAPIEndpoint(cfg.Token, endpoint)
 	if err != nil {
 		return nil, err
 	}
 
+	secret := cfg.WebhookSecret
+	if secret == "" {
+		hash := sha256.Sum256([]byte("webhook:" + cfg.Token))
+		secret = hex.EncodeToString(hash[:])
+	}
+
+	// files are served by the same host under /file/
+	fileEndpoint := tgbotapi.FileEndpoint
+	if strings.HasSuffix(endpoint, "/bot%s/%s") {
+		fileEndpoint = strings.TrimSuffix(endpoint, "/bot%s/%s") + "/file/bot%s/%s"
+	}
+
 	return &Bot{
-		API: bot,
-		h:   h,
+		API:           bot,
+		token:         cfg.Token,
+		fileEndpoint:  fileEndpoint,
+		h:             h,
+		btnH:          btnH,
+		webhookSecret: secret,
+		updatesLog:    cfg.UpdatesLog,
 	}, nil
 }
 
 type Bot struct {
-	API *tgbotapi.BotAPI
-	h   func(UserMsg)
+	API           *tgbotapi.BotAPI
+	token         string
+	fileEndpoint  string
+	h             func(UserMsg)
+	btnH          func(BtnClick)
+	webhookSecret string
+	updatesLog    UpdatesLog
+	// pollingStopped is closed when polling has stopped
+	pollingStopped chan struct{}
 }
 
 func (b *Bot) SetWebhook(webHookUrl string) error {
-	wh, err := tgbotapi.NewWebhook(webHookUrl)
-	if err != nil {
-		return fmt.Errorf("NewWebhook: %w", err)
+	// secret_token is not supported by the tgbotapi webhook config
+	params := tgbotapi.Params{
+		"url":          webHookUrl,
+		"secret_token": b.webhookSecret,
 	}
-	_, err = b.API.Request(wh)
+	if err := params.AddInterface("allowed_updates", allowedUpdates); err != nil {
+		return fmt.Errorf("params.AddInterface: %w", err)
+	}
+	_, err := b.API.MakeRequest("setWebhook", params)
 	if err != nil {
-		return fmt.Errorf("API.Request: %w", err)
+		return fmt.Errorf("API.MakeRequest: %w", err)
 	}
 	info, err := b.API.GetWebhookInfo()
 	if err != nil {
@@ -53,6 +115,7 @@ func (b *Bot) SendMessage(m BotMessage) (int, error) {
 		msg.ParseMode = tgbotapi.ModeMarkdown
 	}
 	msg.ReplyToMessageID = m.ReplyToMsgID
+	msg.DisableWebPagePreview = m.NoPreview
 	if m.Btns != nil {
 		msg.ReplyMarkup = makeInlineKeyboardMarkup(m.Btns)
 	}
@@ -66,6 +129,61 @@ func (b *Bot) SendMessage(m BotMessage) (int, error) {
 	return sentMsg.MessageID, nil
 }
 
+func (b *Bot) SendPhoto(p BotPhoto) (int, error) {
+	photo := tgbotapi.NewPhoto(p.ChatID, tgbotapi.FileBytes{Name: p.Name, Bytes: p.Data})
+	photo.Caption = p.Caption
+
+	sentMsg, err := b.API.Send(photo)
+	if err != nil {
+		return 0, fmt.Errorf("API.Send: %w", err)
+	}
+
+	return sentMsg.MessageID, nil
+}
+
+func (b *Bot) SendDocument(d BotDocument) (int, error) {
+	doc := tgbotapi.NewDocument(d.ChatID, tgbotapi.FileBytes{Name: d.Name, Bytes: d.Data})
+	doc.Caption = d.Caption
+
+	sentMsg, err := b.API.Send(doc)
+	if err != nil {
+		return 0, fmt.Errorf("API.Send: %w", err)
+	}
+
+	return sentMsg.MessageID, nil
+}
+
+func (b *Bot) EditMessage(m BotMessage, msgID int) error {
+	edit := tgbotapi.NewEditMessageText(m.ChatID, msgID, m.Text)
+	if m.TextMarkdown {
+		edit.ParseMode = tgbotapi.ModeMarkdown
+	}
+	if m.Btns != nil {
+		markup := makeInlineKeyboardMarkup(m.Btns)
+		edit.ReplyMarkup = &markup
+	}
+
+	if _, err := b.API.Send(edit); err != nil {
+		return fmt.Errorf("API.Send: %w", err)
+	}
+
+	return nil
+}
+
+// PinMessage silently pins a message in the chat
+func (b *Bot) PinMessage(chatID int64, msgID int) error {
+	pin := tgbotapi.PinChatMessageConfig{
+		ChatID:              chatID,
+		MessageID:           msgID,
+		DisableNotification: true,
+	}
+	if _, err := b.API.Request(pin); err != nil {
+		return fmt.Errorf("API.Request: %w", err)
+	}
+
+	return nil
+}
+
 func (b *Bot) EditBtns(chatID int64, msgID int, newBtns []Btn) error {
 	keyboardEdit := tgbotapi.NewEditMessageReplyMarkup(chatID, msgID, makeInlineKeyboardMarkup(newBtns))
 	_, err := b.API.Send(keyboardEdit)
@@ -77,7 +195,8 @@ func (b *Bot) EditBtns(chatID int64, msgID int, newBtns []Btn) error {
 }
 
 func makeInlineKeyboardMarkup(btns []Btn) tgbotapi.InlineKeyboardMarkup {
-	var rows [][]tgbotapi.InlineKeyboardButton
+	// an empty keyboard must be an empty array to remove buttons
+	rows := [][]tgbotapi.InlineKeyboardButton{}
 	for _, btn := range btns {
 		tgBtn := tgbotapi.NewInlineKeyboardButtonData(btn.Text, btn.ID)
 		row := []tgbotapi.InlineKeyboardButton{tgBtn}
@@ -87,7 +206,48 @@ func makeInlineKeyboardMarkup(btns []Btn) tgbotapi.InlineKeyboardMarkup {
 	return tgbotapi.NewInlineKeyboardMarkup(rows...)
 }
 
+// StartPolling removes the webhook and starts receiving updates with getUpdates long polling until ctx is done
+func (b *Bot) StartPolling(ctx context.Context) error {
+	// getUpdates doesn't work while a webhook is set
+	if _, err := b.API.Request(tgbotapi.DeleteWebhookConfig{}); err != nil {
+		return fmt.Errorf("API.Request(deleteWebhook): %w", err)
+	}
+
+	cfg := tgbotapi.NewUpdate(0)
+	cfg.Timeout = 60
+	cfg.AllowedUpdates = allowedUpdates
+	updates := b.API.GetUpdatesChan(cfg)
+
+	b.pollingStopped = make(chan struct{})
+	go func() {
+		defer close(b.pollingStopped)
+		for {
+			select {
+			case <-ctx.Done():
+				b.API.StopReceivingUpdates()
+				return
+			case upd := <-updates:
+				b.handleUpdate(ctx, upd)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// PollingStopped is closed when polling started by StartPolling has stopped and the last update has been handled
+func (b *Bot) PollingStopped() <-chan struct{} {
+	return b.pollingStopped
+}
+
 func (b *Bot) HandleUpdateRequest(w http.ResponseWriter, r *http.Request) {
+	secret := r.Header.Get(secretTokenHeader)
+	if subtle.ConstantTimeCompare([]byte(secret), []byte(b.webhookSecret)) != 1 {
+		log.Println("webhook request with invalid secret token from", r.RemoteAddr)
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
 	// Parse incoming request
 	upd, err := parseTelegramRequest(r)
 	if err != nil {
@@ -95,17 +255,107 @@ func (b *Bot) HandleUpdateRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if upd.Message == nil {
-		fmt.Println("nil message received")
-		w.WriteHeader(http.StatusOK)
+	b.handleUpdate(r.Context(), upd)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (b *Bot) handleUpdate(ctx context.Context, upd tgbotapi.Update) {
+	if b.updatesLog != nil {
+		handled, err := b.updatesLog.MarkHandled(ctx, upd.UpdateID)
+		if err != nil {
+			// handling an update twice is better than losing it
+			log.Println("updatesLog.MarkHandled error:", err)
+		}
+		if handled {
+			log.Println("skipped already handled update", upd.UpdateID)
+			return
+		}
+	}
+
+	switch {
+	case upd.CallbackQuery != nil:
+		b.handleCallbackQuery(upd.CallbackQuery)
+	case upd.Message != nil:
+		b.h(makeUserMsg(upd.Message, false))
+	case upd.EditedMessage != nil:
+		b.h(makeUserMsg(upd.EditedMessage, true))
+	default:
+		log.Println("ignored update of unsupported type", upd.UpdateID)
+	}
+}
+
+func makeUserMsg(m *tgbotapi.Message, edited bool) UserMsg {
+	msg := UserMsg{
+		ChatID: m.Chat.ID,
+		ID:     m.MessageID,
+		Text:   m.Text,
+		Edited: edited,
+	}
+
+	if m.Document != nil {
+		msg.Text = m.Caption
+		msg.Document = &Document{
+			FileID:   m.Document.FileID,
+			Name:     m.Document.FileName,
+			MimeType: m.Document.MimeType,
+			Size:     m.Document.FileSize,
+		}
+	}
+
+	return msg
+}
+
+// DownloadFile downloads a file sent to the bot, files larger than maxSize are rejected
+func (b *Bot) DownloadFile(fileID string, maxSize int) ([]byte, error) {
+	file, err := b.API.GetFile(tgbotapi.FileConfig{FileID: fileID})
+	if err != nil {
+		return nil, fmt.Errorf("API.GetFile: %w", err)
+	}
+	if file.FileSize > maxSize {
+		return nil, fmt.Errorf("file is too large: %d bytes", file.FileSize)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(b.fileEndpoint, b.token, file.FilePath), nil)
+	if err != nil {
+		return nil, fmt.Errorf("http.NewRequest: %w", err)
+	}
+
+	resp, err := b.API.Client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Client.Do: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status: %s", resp.Status)
 	}
 
-	b.h(UserMsg{
-		ChatID: upd.Message.Chat.ID,
-		ID:     upd.Message.MessageID,
-		Text:   upd.Message.Text,
+	data, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxSize)+1))
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	if len(data) > maxSize {
+		return nil, fmt.Errorf("file is too large")
+	}
+
+	return data, nil
+}
+
+func (b *Bot) handleCallbackQuery(q *tgbotapi.CallbackQuery) {
+	// stops the loading indicator on the button
+	if _, err := b.API.Request(tgbotapi.NewCallback(q.ID, "")); err != nil {
+		log.Println("API.Request(callback) error:", err)
+	}
+
+	if q.Message == nil {
+		return
+	}
+
+	b.btnH(BtnClick{
+		ChatID:    q.Message.Chat.ID,
+		MessageID: q.Message.MessageID,
+		BtnID:     q.Data,
 	})
-	w.WriteHeader(http.StatusOK)
 }
 
 // parseTelegramRequest handles incoming update from the Telegram web hook
@@ -0,0 +1,231 @@
+package tg
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+
+	"github.com/unkeep/alfabooker/tg/tgtest"
+)
+
+type memUpdatesLog struct {
+	mu   sync.Mutex
+	seen map[int]bool
+}
+
+func (l *memUpdatesLog) MarkHandled(_ context.Context, updateID int) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	handled := l.seen[updateID]
+	l.seen[updateID] = true
+	return handled, nil
+}
+
+type recorder struct {
+	mu     sync.Mutex
+	msgs   []UserMsg
+	clicks []BtnClick
+}
+
+func (r *recorder) onMsg(m UserMsg) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.msgs = append(r.msgs, m)
+}
+
+func (r *recorder) onClick(c BtnClick) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.clicks = append(r.clicks, c)
+}
+
+func (r *recorder) received() ([]UserMsg, []BtnClick) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]UserMsg(nil), r.msgs...), append([]BtnClick(nil), r.clicks...)
+}
+
+func newTestBot(t *testing.T) (*Bot, *tgtest.Server, *recorder) {
+	srv := tgtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	rec := &recorder{}
+	bot, err := GetBot(BotConfig{
+		Token:         tgtest.Token,
+		APIEndpoint:   srv.Endpoint(),
+		WebhookSecret: "secret",
+		UpdatesLog:    &memUpdatesLog{seen: map[int]bool{}},
+	}, rec.onMsg, rec.onClick)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return bot, srv, rec
+}
+
+func postUpdate(bot *Bot, secret string, upd tgbotapi.Update) int {
+	body, _ := json.Marshal(upd)
+	req := httptest.NewRequest(http.MethodPost, "/tgupdate", bytes.NewReader(body))
+	req.Header.Set(secretTokenHeader, secret)
+	w := httptest.NewRecorder()
+	bot.HandleUpdateRequest(w, req)
+
+	return w.Code
+}
+
+func TestHandleUpdateRequest(t *testing.T) {
+	t.Run("invalid secret", func(t *testing.T) {
+		bot, srv, rec := newTestBot(t)
+
+		if code := postUpdate(bot, "wrong", srv.NewMessageUpdate(1, "?")); code != http.StatusUnauthorized {
+			t.Errorf("unexpected status %d", code)
+		}
+		if msgs, _ := rec.received(); len(msgs) != 0 {
+			t.Errorf("unexpected messages %+v", msgs)
+		}
+	})
+
+	t.Run("message", func(t *testing.T) {
+		bot, srv, rec := newTestBot(t)
+
+		upd := srv.NewMessageUpdate(1, "?")
+		if code := postUpdate(bot, "secret", upd); code != http.StatusOK {
+			t.Errorf("unexpected status %d", code)
+		}
+		// redelivery
+		if code := postUpdate(bot, "secret", upd); code != http.StatusOK {
+			t.Errorf("unexpected status %d", code)
+		}
+
+		msgs, _ := rec.received()
+		if len(msgs) != 1 || msgs[0].ChatID != 1 || msgs[0].Text != "?" || msgs[0].Edited {
+			t.Errorf("unexpected messages %+v", msgs)
+		}
+	})
+
+	t.Run("edited message", func(t *testing.T) {
+		bot, srv, rec := newTestBot(t)
+
+		upd := srv.NewMessageUpdate(1, "card 100")
+		upd.EditedMessage, upd.Message = upd.Message, nil
+		postUpdate(bot, "secret", upd)
+
+		msgs, _ := rec.received()
+		if len(msgs) != 1 || !msgs[0].Edited {
+			t.Errorf("unexpected messages %+v", msgs)
+		}
+	})
+
+	t.Run("callback query", func(t *testing.T) {
+		bot, srv, rec := newTestBot(t)
+
+		upd := srv.NewMessageUpdate(1, "prompt")
+		upd.CallbackQuery = &tgbotapi.CallbackQuery{ID: "q1", Message: upd.Message, Data: "btn"}
+		upd.Message = nil
+		postUpdate(bot, "secret", upd)
+
+		_, clicks := rec.received()
+		if len(clicks) != 1 || clicks[0].ChatID != 1 || clicks[0].BtnID != "btn" {
+			t.Errorf("unexpected clicks %+v", clicks)
+		}
+		if answers := srv.Requests("answerCallbackQuery"); len(answers) != 1 {
+			t.Errorf("unexpected answers %+v", answers)
+		}
+	})
+
+	t.Run("unsupported update", func(t *testing.T) {
+		bot, _, rec := newTestBot(t)
+
+		if code := postUpdate(bot, "secret", tgbotapi.Update{UpdateID: 42}); code != http.StatusOK {
+			t.Errorf("unexpected status %d", code)
+		}
+		if msgs, clicks := rec.received(); len(msgs) != 0 || len(clicks) != 0 {
+			t.Errorf("unexpected updates %+v %+v", msgs, clicks)
+		}
+	})
+}
+
+func TestStartPolling(t *testing.T) {
+	bot, srv, rec := newTestBot(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := bot.StartPolling(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if reqs := srv.Requests("deleteWebhook"); len(reqs) != 1 {
+		t.Errorf("webhook is not deleted")
+	}
+
+	srv.InjectUpdate(srv.NewMessageUpdate(1, "?"))
+
+	deadline := time.Now().Add(time.Second * 5)
+	for time.Now().Before(deadline) {
+		if msgs, _ := rec.received(); len(msgs) == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond * 10)
+	}
+	if msgs, _ := rec.received(); len(msgs) != 1 {
+		t.Fatal("update is not received")
+	}
+
+	cancel()
+	select {
+	case <-bot.PollingStopped():
+	case <-time.After(time.Second * 5):
+		t.Error("polling is not stopped")
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	bot, srv, _ := newTestBot(t)
+
+	msgID, err := bot.SendMessage(BotMessage{ChatID: 1, Text: "hello", Btns: []Btn{{ID: "a", Text: "A"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msgID == 0 {
+		t.Error("message ID expected")
+	}
+
+	if texts := srv.SentTexts(1); len(texts) != 1 || texts[0] != "hello" {
+		t.Errorf("unexpected texts %+v", texts)
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	bot, srv, rec := newTestBot(t)
+
+	fileID := srv.AddFile([]byte("date,amount\n"))
+	upd := srv.NewDocumentUpdate(1, fileID, "statement.csv", "caption")
+	if code := postUpdate(bot, bot.webhookSecret, upd); code != http.StatusOK {
+		t.Fatalf("unexpected status %d", code)
+	}
+
+	msgs, _ := rec.received()
+	if len(msgs) != 1 || msgs[0].Document == nil || msgs[0].Document.Name != "statement.csv" || msgs[0].Text != "caption" {
+		t.Fatalf("unexpected messages %+v", msgs)
+	}
+
+	data, err := bot.DownloadFile(msgs[0].Document.FileID, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "date,amount\n" {
+		t.Errorf("unexpected data %q", data)
+	}
+
+	if _, err := bot.DownloadFile(fileID, 4); err == nil {
+		t.Error("expected too large file error")
+	}
+}
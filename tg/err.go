@@ -0,0 +1,50 @@
+package tg
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// IsMessageNotFound reports whether the error is caused by a deleted or unknown message
+func IsMessageNotFound(err error) bool {
+	return hasDescription(err, "message to edit not found") ||
+		hasDescription(err, "message to pin not found")
+}
+
+// IsMessageNotModified reports whether the error is caused by editing a message without changes
+func IsMessageNotModified(err error) bool {
+	return hasDescription(err, "message is not modified")
+}
+
+// RetryAfter returns how long to wait before retrying a request rejected by flood control
+func RetryAfter(err error) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) || apiErr.RetryAfter == 0 {
+		return 0, false
+	}
+
+	return time.Duration(apiErr.RetryAfter) * time.Second, true
+}
+
+// IsBadRequest reports whether the request has been rejected as invalid, so retrying it makes no sense
+func IsBadRequest(err error) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return apiErr.Code == http.StatusBadRequest || apiErr.Code == http.StatusForbidden
+}
+
+func hasDescription(err error, description string) bool {
+	var apiErr *tgbotapi.Error
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+
+	return strings.Contains(apiErr.Message, description)
+}
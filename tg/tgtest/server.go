@@ -0,0 +1,341 @@
+// Package tgtest provides an in-process fake of the Telegram Bot API for tests.
+package tgtest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// Token is a bot token accepted by the fake server
+const Token = "123456:test-token"
+
+// BotID is an ID of the fake bot
+const BotID = 123456
+
+// pollWait is how long getUpdates waits for new updates, much shorter than real long polling
+const pollWait = 50 * time.Millisecond
+
+// Request is a recorded Bot API call
+type Request struct {
+	Method string
+	Params map[string]string
+	Files  map[string][]byte
+}
+
+// ChatID returns the chat_id param of the request
+func (r Request) ChatID() int64 {
+	id, _ := strconv.ParseInt(r.Params["chat_id"], 10, 64)
+	return id
+}
+
+// Failure is an error response returned instead of a successful one
+type Failure struct {
+	Code        int
+	Description string
+	RetryAfter  int
+}
+
+// Server is a fake Telegram Bot API server
+type Server struct {
+	srv *httptest.Server
+
+	mu        sync.Mutex
+	requests  []Request
+	updates   []tgbotapi.Update
+	failures  map[string][]Failure
+	files     map[string][]byte
+	nextMsgID int
+	nextUpdID int
+}
+
+// NewServer starts a fake Bot API server
+func NewServer() *Server {
+	s := &Server{
+		failures:  map[string][]Failure{},
+		files:     map[string][]byte{},
+		nextMsgID: 1,
+		nextUpdID: 1,
+	}
+	s.srv = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+
+	return s
+}
+
+// Close shuts the server down
+func (s *Server) Close() {
+	s.srv.Close()
+}
+
+// Endpoint returns a Bot API endpoint format of the server to be used instead of tgbotapi.APIEndpoint
+func (s *Server) Endpoint() string {
+	return s.srv.URL + "/bot%s/%s"
+}
+
+// Requests returns all recorded calls of the given method, all calls if method is empty
+func (s *Server) Requests(method string) []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var res []Request
+	for _, r := range s.requests {
+		if method == "" || r.Method == method {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// SentTexts returns texts of messages sent to the chat
+func (s *Server) SentTexts(chatID int64) []string {
+	var texts []string
+	for _, r := range s.Requests("sendMessage") {
+		if r.ChatID() == chatID {
+			texts = append(texts, r.Params["text"])
+		}
+	}
+	return texts
+}
+
+// Reset forgets recorded calls
+func (s *Server) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.requests = nil
+}
+
+// Fail makes the next call of the method return the failure
+func (s *Server) Fail(method string, f Failure) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures[method] = append(s.failures[method], f)
+}
+
+// InjectUpdate queues an update to be returned by getUpdates, the update ID is assigned if not set
+func (s *Server) InjectUpdate(upd tgbotapi.Update) tgbotapi.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if upd.UpdateID == 0 {
+		upd.UpdateID = s.nextUpdID
+	}
+	if upd.UpdateID >= s.nextUpdID {
+		s.nextUpdID = upd.UpdateID + 1
+	}
+	s.updates = append(s.updates, upd)
+
+	return upd
+}
+
+// NewMessageUpdate builds a text message update from the chat, the update is not queued
+func (s *Server) NewMessageUpdate(chatID int64, text string) tgbotapi.Update {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upd := tgbotapi.Update{
+		UpdateID: s.nextUpdID,
+		Message: &tgbotapi.Message{
+			MessageID: s.nextMsgID,
+			Chat:      &tgbotapi.Chat{ID: chatID, Type: "private"},
+			Date:      int(time.Now().Unix()),
+			Text:      text,
+		},
+	}
+	s.nextUpdID++
+	s.nextMsgID++
+
+	return upd
+}
+
+// AddFile stores a file to be downloaded by the bot and returns its file ID
+func (s *Server) AddFile(data []byte) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fileID := "file" + strconv.Itoa(len(s.files)+1)
+	s.files[fileID] = data
+
+	return fileID
+}
+
+// NewDocumentUpdate builds a message update with a document added by AddFile, the update is not queued
+func (s *Server) NewDocumentUpdate(chatID int64, fileID, name, caption string) tgbotapi.Update {
+	upd := s.NewMessageUpdate(chatID, "")
+	upd.Message.Caption = caption
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	upd.Message.Document = &tgbotapi.Document{FileID: fileID, FileUniqueID: fileID, FileName: name, FileSize: len(s.files[fileID])}
+
+	return upd
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	filePrefix := "/file/bot" + Token + "/documents/"
+	if strings.HasPrefix(r.URL.Path, filePrefix) {
+		s.mu.Lock()
+		data, ok := s.files[strings.TrimPrefix(r.URL.Path, filePrefix)]
+		s.mu.Unlock()
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write(data)
+		return
+	}
+
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	if len(parts) != 2 || parts[0] != "bot"+Token {
+		writeError(w, Failure{Code: http.StatusUnauthorized, Description: "Unauthorized"})
+		return
+	}
+	method := parts[1]
+
+	req, err := parseRequest(method, r)
+	if err != nil {
+		writeError(w, Failure{Code: http.StatusBadRequest, Description: err.Error()})
+		return
+	}
+
+	if method == "getUpdates" {
+		s.serveUpdates(w, req)
+		return
+	}
+
+	s.mu.Lock()
+	s.requests = append(s.requests, req)
+	if failures := s.failures[method]; len(failures) > 0 {
+		s.failures[method] = failures[1:]
+		s.mu.Unlock()
+		writeError(w, failures[0])
+		return
+	}
+	s.mu.Unlock()
+
+	switch method {
+	case "getMe":
+		writeResult(w, tgbotapi.User{ID: BotID, IsBot: true, FirstName: "Test", UserName: "test_bot"})
+	case "sendMessage", "sendPhoto", "sendDocument", "editMessageText", "editMessageReplyMarkup":
+		writeResult(w, s.newMessage(req))
+	case "getWebhookInfo":
+		writeResult(w, tgbotapi.WebhookInfo{})
+	case "getFile":
+		s.serveFile(w, req)
+	default:
+		writeResult(w, true)
+	}
+}
+
+func (s *Server) serveUpdates(w http.ResponseWriter, req Request) {
+	offset, _ := strconv.Atoi(req.Params["offset"])
+
+	deadline := time.Now().Add(pollWait)
+	for {
+		s.mu.Lock()
+		var pending []tgbotapi.Update
+		for _, upd := range s.updates {
+			if upd.UpdateID >= offset {
+				pending = append(pending, upd)
+			}
+		}
+		s.mu.Unlock()
+
+		if len(pending) > 0 || time.Now().After(deadline) {
+			if pending == nil {
+				pending = []tgbotapi.Update{}
+			}
+			writeResult(w, pending)
+			return
+		}
+		time.Sleep(pollWait / 5)
+	}
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, req Request) {
+	fileID := req.Params["file_id"]
+
+	s.mu.Lock()
+	data, ok := s.files[fileID]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, Failure{Code: http.StatusBadRequest, Description: "Bad Request: invalid file_id"})
+		return
+	}
+
+	writeResult(w, tgbotapi.File{FileID: fileID, FileUniqueID: fileID, FileSize: len(data), FilePath: "documents/" + fileID})
+}
+
+func (s *Server) newMessage(req Request) tgbotapi.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	msgID, _ := strconv.Atoi(req.Params["message_id"])
+	if msgID == 0 {
+		msgID = s.nextMsgID
+		s.nextMsgID++
+	}
+
+	return tgbotapi.Message{
+		MessageID: msgID,
+		From:      &tgbotapi.User{ID: BotID, IsBot: true},
+		Chat:      &tgbotapi.Chat{ID: req.ChatID()},
+		Date:      int(time.Now().Unix()),
+		Text:      req.Params["text"],
+	}
+}
+
+func parseRequest(method string, r *http.Request) (Request, error) {
+	req := Request{Method: method, Params: map[string]string{}, Files: map[string][]byte{}}
+
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		if err := r.ParseMultipartForm(32 << 20); err != nil {
+			return req, err
+		}
+		for name, headers := range r.MultipartForm.File {
+			f, err := headers[0].Open()
+			if err != nil {
+				return req, err
+			}
+			data, err := io.ReadAll(f)
+			_ = f.Close()
+			if err != nil {
+				return req, err
+			}
+			req.Files[name] = data
+		}
+	} else if err := r.ParseForm(); err != nil {
+		return req, err
+	}
+
+	for name, values := range r.Form {
+		req.Params[name] = values[0]
+	}
+
+	return req, nil
+}
+
+func writeResult(w http.ResponseWriter, result interface{}) {
+	data, _ := json.Marshal(result)
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(tgbotapi.APIResponse{Ok: true, Result: data})
+}
+
+func writeError(w http.ResponseWriter, f Failure) {
+	resp := tgbotapi.APIResponse{Ok: false, ErrorCode: f.Code, Description: f.Description}
+	if f.RetryAfter != 0 {
+		resp.Parameters = &tgbotapi.ResponseParameters{RetryAfter: f.RetryAfter}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.Code)
+	_ = json.NewEncoder(w).Encode(resp)
+}
@@ -1,10 +1,34 @@
 package tg
 
+// UpdateMode is a way the bot receives updates
+type UpdateMode string
+
+// Update modes
+const (
+	// UpdateModeWebhook receives updates pushed by Telegram to a public HTTPS URL
+	UpdateModeWebhook UpdateMode = "webhook"
+	// UpdateModePolling pulls updates with getUpdates long polling
+	UpdateModePolling UpdateMode = "polling"
+)
+
 // UserMsg is a plain user text message
 type UserMsg struct {
 	ChatID int64
 	ID     int
-	Text   string
+	// Text is the message text or the document caption
+	Text string
+	// Edited is set when the message is an edit of a previously sent one
+	Edited bool
+	// Document is a file sent with the message
+	Document *Document
+}
+
+// Document is a file sent by a user, its content is fetched with Bot.DownloadFile
+type Document struct {
+	FileID   string
+	Name     string
+	MimeType string
+	Size     int
 }
 
 // Btn is a telegram inline btn
@@ -15,6 +39,7 @@ type Btn struct {
 
 // BtnClick is a telegram inline btn reply
 type BtnClick struct {
+	ChatID    int64
 	MessageID int
 	BtnID     string
 }
@@ -25,4 +50,20 @@ type BotMessage struct {
 	Text         string
 	TextMarkdown bool
 	Btns         []Btn
+	// NoPreview disables the link preview, which Telegram fetches by opening the link
+	NoPreview bool
+}
+
+type BotPhoto struct {
+	ChatID  int64
+	Name    string
+	Data    []byte
+	Caption string
+}
+
+type BotDocument struct {
+	ChatID  int64
+	Name    string
+	Data    []byte
+	Caption string
 }
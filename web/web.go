@@ -0,0 +1,310 @@
+// Package web serves the HTML dashboard.
+package web
+
+import (
+	"context"
+	"embed"
+	"errors"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/unkeep/alfabooker/auth"
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/chart"
+	"github.com/unkeep/alfabooker/db"
+	"github.com/unkeep/alfabooker/i18n"
+)
+
+// PathPrefix is a path prefix of the dashboard
+const PathPrefix = "/app"
+
+// LoginPath is a path of login links issued by the bot
+const LoginPath = PathPrefix + "/login"
+
+// sessionCookie is a name of the web session cookie
+const sessionCookie = "ab_session"
+
+// recentTransactions is a number of transactions shown on the dashboard
+const recentTransactions = 20
+
+//go:embed templates
+var templatesFS embed.FS
+
+//go:embed static
+var staticFS embed.FS
+
+var templates = template.Must(template.ParseFS(templatesFS, "templates/*.html"))
+
+// paths are dashboard paths besides static assets
+var paths = []string{PathPrefix, LoginPath, PathPrefix + "/logout", PathPrefix + "/chart.png"}
+
+type handler struct {
+	budgetDomain *budget.Domain
+	sessions     *auth.Sessions
+	printer      func(ctx context.Context) i18n.Printer
+	static       http.Handler
+}
+
+// NewHandler creates the dashboard handler, printer returns a printer of the dashboard language
+func NewHandler(budgetDomain *budget.Domain, sessions *auth.Sessions, printer func(ctx context.Context) i18n.Printer) http.Handler {
+	static, err := fs.Sub(staticFS, "static")
+	if err != nil {
+		panic(err)
+	}
+
+	return &handler{
+		budgetDomain: budgetDomain,
+		sessions:     sessions,
+		printer:      printer,
+		static:       http.StripPrefix(PathPrefix+"/static/", http.FileServer(http.FS(static))),
+	}
+}
+
+// RouteLabel returns a route of the request path to be used as a metric label, "other" for unknown paths
+func RouteLabel(path string) string {
+	if strings.HasPrefix(path, PathPrefix+"/static/") {
+		return PathPrefix + "/static"
+	}
+	for _, p := range paths {
+		if p == path {
+			return path
+		}
+	}
+	return "other"
+}
+
+func (h *handler) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	path := request.URL.Path
+
+	switch {
+	case strings.HasPrefix(path, PathPrefix+"/static/"):
+		h.static.ServeHTTP(writer, request)
+		return
+	case path == LoginPath && request.Method == http.MethodGet:
+		h.confirmLogin(writer, request)
+		return
+	case path == LoginPath && request.Method == http.MethodPost:
+		h.login(writer, request)
+		return
+	case path == PathPrefix+"/logout" && request.Method == http.MethodPost:
+		h.logout(writer, request)
+		return
+	}
+
+	if path != PathPrefix && path != PathPrefix+"/chart.png" {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if request.Method != http.MethodGet {
+		writer.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !h.authenticated(request) {
+		h.render(writer, request, http.StatusUnauthorized, "login.html", i18n.WebLoginRequired)
+		return
+	}
+
+	if path == PathPrefix+"/chart.png" {
+		h.chart(writer, request)
+		return
+	}
+	h.dashboard(writer, request)
+}
+
+func (h *handler) authenticated(request *http.Request) bool {
+	cookie, err := request.Cookie(sessionCookie)
+	if err != nil {
+		return false
+	}
+
+	err = h.sessions.Authenticate(request.Context(), cookie.Value)
+	if err != nil && err != auth.ErrUnauthorized {
+		log.Println("sessions.Authenticate:", err)
+	}
+
+	return err == nil
+}
+
+// confirmLogin asks to log in with the link, since link previews and scanners open links with GET
+// and would use the one time secret up
+func (h *handler) confirmLogin(writer http.ResponseWriter, request *http.Request) {
+	secret := request.URL.Query().Get("t")
+	if secret == "" {
+		h.render(writer, request, http.StatusUnauthorized, "login.html", i18n.WebLoginRequired)
+		return
+	}
+
+	p := h.printer(request.Context())
+	h.execute(writer, http.StatusOK, "login.html", view{p: p, Message: p.T(i18n.WebLoginConfirm), Secret: secret})
+}
+
+func (h *handler) login(writer http.ResponseWriter, request *http.Request) {
+	secret, err := h.sessions.Login(request.Context(), request.PostFormValue("t"))
+	if err == auth.ErrUnauthorized {
+		h.render(writer, request, http.StatusUnauthorized, "login.html", i18n.WebLoginExpired)
+		return
+	}
+	if err != nil {
+		log.Println("sessions.Login:", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	http.SetCookie(writer, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    secret,
+		Path:     PathPrefix,
+		Expires:  time.Now().Add(auth.SessionTTL),
+		HttpOnly: true,
+		Secure:   isHTTPS(request),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(writer, request, PathPrefix, http.StatusSeeOther)
+}
+
+func (h *handler) logout(writer http.ResponseWriter, request *http.Request) {
+	if cookie, err := request.Cookie(sessionCookie); err == nil {
+		if err := h.sessions.Logout(request.Context(), cookie.Value); err != nil {
+			log.Println("sessions.Logout:", err)
+		}
+	}
+
+	http.SetCookie(writer, &http.Cookie{
+		Name:     sessionCookie,
+		Path:     PathPrefix,
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   isHTTPS(request),
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(writer, request, PathPrefix, http.StatusSeeOther)
+}
+
+func (h *handler) chart(writer http.ResponseWriter, request *http.Request) {
+	periods, err := h.budgetDomain.GetPeriods(request.Context(), 0)
+	if errors.Is(err, db.ErrNotFound) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("budgetDomain.GetPeriods:", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	data, err := chart.RenderBalance(periods[0])
+	if errors.Is(err, chart.ErrEmptyPeriod) {
+		writer.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Println("chart.RenderBalance:", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "image/png")
+	writer.Header().Set("Cache-Control", "no-store")
+	_, _ = writer.Write(data)
+}
+
+// view is data of a page
+type view struct {
+	p       i18n.Printer
+	Message string
+	// Secret is a login link secret to be submitted
+	Secret string
+	Stat   *budget.Statistics
+	Txs    []budget.Transaction
+}
+
+// T translates a message key
+func (v view) T(key string, args ...interface{}) string {
+	return v.p.T(i18n.Key(key), args...)
+}
+
+// Lang returns a language code of the page
+func (v view) Lang() string {
+	return string(v.p.Lang())
+}
+
+// Number formats an integer amount
+func (v view) Number(val float64) string {
+	return v.p.Number(val)
+}
+
+// SignedNumber formats an integer amount with a sign
+func (v view) SignedNumber(val float64) string {
+	return v.p.SignedNumber(val)
+}
+
+// Decimal formats an amount with cents
+func (v view) Decimal(val float64) string {
+	return v.p.Decimal(val, 2)
+}
+
+// Days formats a number of days
+func (v view) Days(val float64) string {
+	return v.p.Decimal(val, 1)
+}
+
+// Date formats a unix time date
+func (v view) Date(at int64) string {
+	return v.p.Date(time.Unix(at, 0))
+}
+
+// DateTime formats a unix time
+func (v view) DateTime(at int64) string {
+	return v.p.DateTime(time.Unix(at, 0))
+}
+
+func (h *handler) dashboard(writer http.ResponseWriter, request *http.Request) {
+	ctx := request.Context()
+	v := view{p: h.printer(ctx)}
+
+	var err error
+	// the dashboard is shown without stats until a budget is started
+	v.Stat, err = h.budgetDomain.GetStat(ctx)
+	if err != nil && !errors.Is(err, db.ErrNotFound) {
+		log.Println("budgetDomain.GetStat:", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	txs, err := h.budgetDomain.GetTransactions(ctx, 0, 0)
+	if err != nil {
+		log.Println("budgetDomain.GetTransactions:", err)
+		writer.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	for i := len(txs) - 1; i >= 0 && len(v.Txs) < recentTransactions; i-- {
+		v.Txs = append(v.Txs, txs[i])
+	}
+
+	h.execute(writer, http.StatusOK, "dashboard.html", v)
+}
+
+func (h *handler) render(writer http.ResponseWriter, request *http.Request, status int, name string, message i18n.Key) {
+	p := h.printer(request.Context())
+	h.execute(writer, status, name, view{p: p, Message: p.T(message)})
+}
+
+func (h *handler) execute(writer http.ResponseWriter, status int, name string, v view) {
+	writer.Header().Set("Content-Type", "text/html; charset=utf-8")
+	writer.Header().Set("Cache-Control", "no-store")
+	writer.Header().Set("X-Frame-Options", "DENY")
+	writer.WriteHeader(status)
+
+	if err := templates.ExecuteTemplate(writer, name, v); err != nil {
+		log.Println("ExecuteTemplate:", err)
+	}
+}
+
+func isHTTPS(request *http.Request) bool {
+	return request.TLS != nil || request.Header.Get("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,85 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/i18n"
+)
+
+func TestTemplates(t *testing.T) {
+	p := i18n.NewPrinter(i18n.En)
+
+	v := view{
+		p: p,
+		Stat: &budget.Statistics{
+			BudgetAmount:     1000,
+			TotalBalance:     600,
+			BalanceDeviation: -50,
+			BudgetStartedAt:  1700000000,
+			BudgetExpiresAt:  1702592000,
+		},
+		Txs: []budget.Transaction{{At: 1700000000, Amount: -12.5, Currency: "GEL", Merchant: "<SHOP>"}},
+	}
+
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, "dashboard.html", v); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{p.T(i18n.WebTransactions), "&lt;SHOP&gt;", p.Decimal(-12.5, 2), "/app/chart.png"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("dashboard does not contain %q", want)
+		}
+	}
+
+	// a budget which has not been started has no chart
+	buf.Reset()
+	v.Stat = &budget.Statistics{BudgetAmount: 1000}
+	if err := templates.ExecuteTemplate(&buf, "dashboard.html", v); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "/app/chart.png") {
+		t.Error("dashboard shows the chart of an empty period")
+	}
+
+	buf.Reset()
+	v = view{p: p, Message: p.T(i18n.WebLoginRequired)}
+	if err := templates.ExecuteTemplate(&buf, "login.html", v); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), p.T(i18n.WebLoginRequired)) {
+		t.Errorf("login page does not contain the message: %s", buf.String())
+	}
+}
+
+func TestRouteLabel(t *testing.T) {
+	for path, want := range map[string]string{
+		"/app":                "/app",
+		"/app/login":          "/app/login",
+		"/app/static/app.css": "/app/static",
+		"/app/secret":         "other",
+	} {
+		if got := RouteLabel(path); got != want {
+			t.Errorf("RouteLabel(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestConfirmLogin(t *testing.T) {
+	// sessions are nil, so the secret must not be used by GET
+	h := &handler{printer: func(context.Context) i18n.Printer { return i18n.NewPrinter(i18n.En) }}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, LoginPath+"?t=abc", nil))
+
+	body := w.Body.String()
+	if w.Code != http.StatusOK || !strings.Contains(body, `method="post"`) || !strings.Contains(body, `value="abc"`) {
+		t.Errorf("unexpected response %d %s", w.Code, body)
+	}
+}
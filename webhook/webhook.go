@@ -0,0 +1,351 @@
+// Package webhook delivers budget events to registered external URLs as signed JSON POSTs.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/unkeep/alfabooker/budget"
+	"github.com/unkeep/alfabooker/db"
+)
+
+// Headers of webhook requests, signed the same way as SMS ingestion requests
+const (
+	HeaderEvent     = "X-Event"
+	HeaderTimestamp = "X-Timestamp"
+	HeaderNonce     = "X-Nonce"
+	HeaderSignature = "X-Signature"
+)
+
+const (
+	// maxAttempts is a number of attempts after which a delivery is considered failed
+	maxAttempts = 10
+	// maxRetryDelay caps the exponential backoff
+	maxRetryDelay = time.Hour
+	// lease is how long a claimed delivery is not claimed again
+	lease = time.Minute
+	// pollInterval is how often due deliveries are checked without explicit wakeups
+	pollInterval = time.Second * 15
+	// logTTL is how long deliveries are kept in the log
+	logTTL = 30 * 24 * time.Hour
+	// requestTimeout limits a single delivery attempt
+	requestTimeout = time.Second * 10
+)
+
+// ErrInvalidURL is returned when registering a webhook with a URL other than absolute http(s)
+var ErrInvalidURL = errors.New("invalid webhook URL")
+
+// Payload is a body of webhook requests
+type Payload struct {
+	// ID identifies the delivery, it is the same for all attempts
+	ID    string       `json:"id"`
+	Event budget.Event `json:"event"`
+}
+
+// Dispatcher registers webhooks and delivers events to them with retries
+type Dispatcher struct {
+	hooks      *db.WebhooksRepo
+	deliveries *db.DeliveriesRepo
+	client     *http.Client
+
+	wake chan struct{}
+}
+
+// NewDispatcher creates a dispatcher, Run starts deliveries
+func NewDispatcher(hooks *db.WebhooksRepo, deliveries *db.DeliveriesRepo) *Dispatcher {
+	return &Dispatcher{
+		hooks:      hooks,
+		deliveries: deliveries,
+		client:     &http.Client{Timeout: requestTimeout},
+		wake:       make(chan struct{}, 1),
+	}
+}
+
+// Sign returns a signature of the request body, it is sent in the X-Signature header
+func Sign(secret string, timestamp int64, nonce string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = fmt.Fprintf(mac, "%d\n%s\n", timestamp, nonce)
+	_, _ = mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// ParseEvents parses a comma separated list of event types, an empty list means all events
+func ParseEvents(s string) ([]string, error) {
+	var events []string
+	for _, e := range strings.Split(s, ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+		known := false
+		for _, t := range budget.EventTypes {
+			known = known || string(t) == e
+		}
+		if !known {
+			return nil, fmt.Errorf("unknown event: %s", e)
+		}
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+// Add registers a webhook and returns it together with its signing secret
+func (d *Dispatcher) Add(ctx context.Context, rawURL string, events []string) (db.Webhook, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return db.Webhook{}, ErrInvalidURL
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return db.Webhook{}, fmt.Errorf("rand.Read: %w", err)
+	}
+
+	w := db.Webhook{
+		URL:       u.String(),
+		Secret:    hex.EncodeToString(buf),
+		Events:    events,
+		CreatedAt: time.Now().Unix(),
+	}
+	w.ID, err = d.hooks.Add(ctx, w)
+	if err != nil {
+		return w, fmt.Errorf("WebhooksRepo.Add: %w", err)
+	}
+
+	return w, nil
+}
+
+// List returns registered webhooks
+func (d *Dispatcher) List(ctx context.Context) ([]db.Webhook, error) {
+	hooks, err := d.hooks.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("WebhooksRepo.GetAll: %w", err)
+	}
+
+	return hooks, nil
+}
+
+// Remove deletes a webhook by its hex ID, pending deliveries are still attempted
+func (d *Dispatcher) Remove(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return db.ErrNotFound
+	}
+
+	if err := d.hooks.Delete(ctx, oid); err != nil {
+		return fmt.Errorf("WebhooksRepo.Delete: %w", err)
+	}
+
+	return nil
+}
+
+// Log returns up to n most recent deliveries
+func (d *Dispatcher) Log(ctx context.Context, n int) ([]db.Delivery, error) {
+	deliveries, err := d.deliveries.GetLast(ctx, n)
+	if err != nil {
+		return nil, fmt.Errorf("DeliveriesRepo.GetLast: %w", err)
+	}
+
+	return deliveries, nil
+}
+
+// Publish queues deliveries of the event to the webhooks subscribed to it
+func (d *Dispatcher) Publish(ctx context.Context, e budget.Event) error {
+	hooks, err := d.hooks.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("WebhooksRepo.GetAll: %w", err)
+	}
+
+	queued := false
+	for _, w := range hooks {
+		if !subscribed(w, e.Type) {
+			continue
+		}
+
+		now := time.Now()
+		id := primitive.NewObjectID()
+		payload, err := json.Marshal(Payload{ID: id.Hex(), Event: e})
+		if err != nil {
+			return fmt.Errorf("json.Marshal: %w", err)
+		}
+
+		delivery := db.Delivery{
+			ID:            id,
+			WebhookID:     w.ID,
+			URL:           w.URL,
+			Event:         string(e.Type),
+			Payload:       string(payload),
+			Status:        db.DeliveryPending,
+			NextAttemptAt: now.Unix(),
+			CreatedAt:     now.Unix(),
+			ExpiresAt:     now.Add(logTTL),
+		}
+		if err := d.deliveries.Add(ctx, delivery); err != nil {
+			return fmt.Errorf("DeliveriesRepo.Add: %w", err)
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+
+	return nil
+}
+
+// Run delivers due deliveries until ctx is done
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.deliverDue(ctx); err != nil {
+			log.Println("webhook.deliverDue:", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *Dispatcher) deliverDue(ctx context.Context) error {
+	// secrets are looked up on every round, so removed webhooks are not signed with stale secrets
+	secrets := map[primitive.ObjectID]string{}
+	hooks, err := d.hooks.GetAll(ctx)
+	if err != nil {
+		return fmt.Errorf("WebhooksRepo.GetAll: %w", err)
+	}
+	for _, w := range hooks {
+		secrets[w.ID] = w.Secret
+	}
+
+	for ctx.Err() == nil {
+		now := time.Now()
+		delivery, err := d.deliveries.ClaimDue(ctx, now.Unix(), now.Add(lease).Unix())
+		if err == db.ErrNotFound {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("DeliveriesRepo.ClaimDue: %w", err)
+		}
+
+		secret, ok := secrets[delivery.WebhookID]
+		if ok {
+			d.deliver(ctx, &delivery, secret)
+		} else {
+			delivery.Status = db.DeliveryFailed
+			delivery.LastError = "webhook has been removed"
+		}
+
+		if err := d.deliveries.Save(ctx, delivery); err != nil {
+			return fmt.Errorf("DeliveriesRepo.Save: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, delivery *db.Delivery, secret string) {
+	delivery.Attempts++
+
+	status, err := d.post(ctx, delivery, secret)
+	delivery.LastStatusCode = status
+	if err == nil {
+		delivery.Status = db.DeliveryDelivered
+		delivery.DeliveredAt = time.Now().Unix()
+		delivery.LastError = ""
+		return
+	}
+
+	log.Printf("webhook: attempt %d to deliver %s to %s failed: %s\n", delivery.Attempts, delivery.ID.Hex(), delivery.URL, err.Error())
+	delivery.LastError = err.Error()
+	if delivery.Attempts >= maxAttempts || !retriable(status) {
+		delivery.Status = db.DeliveryFailed
+		return
+	}
+	delivery.NextAttemptAt = time.Now().Add(retryDelay(delivery.Attempts)).Unix()
+}
+
+// post sends the delivery and returns the response status
+func (d *Dispatcher) post(ctx context.Context, delivery *db.Delivery, secret string) (int, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderNonce, delivery.ID.Hex())
+	req.Header.Set(HeaderSignature, Sign(secret, timestamp, delivery.ID.Hex(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return resp.StatusCode, nil
+}
+
+func subscribed(w db.Webhook, t budget.EventType) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == string(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// retriable reports whether a delivery failed with the status can succeed later,
+// client errors other than timeouts and rate limits are not retried
+func retriable(status int) bool {
+	if status == 0 || status >= 500 {
+		return true
+	}
+	return status == http.StatusRequestTimeout || status == http.StatusTooManyRequests
+}
+
+// retryDelay returns an exponential delay before the next attempt
+func retryDelay(attempts int) time.Duration {
+	delay := time.Second * 5 << uint(attempts)
+	if delay > maxRetryDelay || delay <= 0 {
+		delay = maxRetryDelay
+	}
+
+	return delay
+}
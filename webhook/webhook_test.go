@@ -0,0 +1,82 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+
+	"github.com/unkeep/alfabooker/db"
+)
+
+func TestDeliver(t *testing.T) {
+	const secret = "secret"
+
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		if r.Header.Get(HeaderSignature) != Sign(secret, ts, r.Header.Get(HeaderNonce), body) {
+			t.Error("invalid signature")
+		}
+		if r.Header.Get(HeaderEvent) != "budget_started" {
+			t.Errorf("unexpected event %s", r.Header.Get(HeaderEvent))
+		}
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	d := NewDispatcher(nil, nil)
+	newDelivery := func() *db.Delivery {
+		return &db.Delivery{ID: primitive.NewObjectID(), URL: srv.URL, Event: "budget_started", Payload: `{}`, Status: db.DeliveryPending}
+	}
+
+	tests := []struct {
+		name   string
+		status int
+		want   string
+	}{
+		{"delivered", http.StatusNoContent, db.DeliveryDelivered},
+		{"server error is retried", http.StatusBadGateway, db.DeliveryPending},
+		{"rate limit is retried", http.StatusTooManyRequests, db.DeliveryPending},
+		{"client error fails", http.StatusNotFound, db.DeliveryFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status = tt.status
+			delivery := newDelivery()
+			d.deliver(context.Background(), delivery, secret)
+
+			if delivery.Status != tt.want || delivery.LastStatusCode != tt.status || delivery.Attempts != 1 {
+				t.Errorf("unexpected delivery %+v", delivery)
+			}
+		})
+	}
+
+	t.Run("attempts exhausted", func(t *testing.T) {
+		status = http.StatusBadGateway
+		delivery := newDelivery()
+		delivery.Attempts = maxAttempts - 1
+		d.deliver(context.Background(), delivery, secret)
+
+		if delivery.Status != db.DeliveryFailed {
+			t.Errorf("unexpected status %s", delivery.Status)
+		}
+	})
+}
+
+func TestParseEvents(t *testing.T) {
+	events, err := ParseEvents("transaction_added, alert_raised")
+	if err != nil || len(events) != 2 {
+		t.Errorf("unexpected events %v, %v", events, err)
+	}
+
+	if _, err := ParseEvents("unknown"); err == nil {
+		t.Error("unknown events must fail")
+	}
+}
@@ -0,0 +1,65 @@
+package widget
+
+import (
+	"fmt"
+	"io"
+)
+
+const (
+	okColor      = "#16a34a"
+	warnColor    = "#f59e0b"
+	badColor     = "#dc2626"
+	labelColor   = "#555"
+	elapsedColor = "#3b82f6"
+	trackColor   = "#e5e7eb"
+)
+
+// statusColor is green when the balance is ahead of time, amber when slightly behind and red otherwise
+func statusColor(p Progress) string {
+	ahead := p.BalancePct - (100 - p.ElapsedPct)
+	switch {
+	case ahead >= 0:
+		return okColor
+	case ahead >= -10:
+		return warnColor
+	}
+	return badColor
+}
+
+// WriteBadge writes a shields-like SVG badge of balance and elapsed percents
+func WriteBadge(w io.Writer, p Progress) error {
+	value := fmt.Sprintf("%d%% / %d%%", p.BalancePct, p.ElapsedPct)
+	labelWidth, valueWidth := 56, 8+7*len(value)
+	width := labelWidth + valueWidth
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="budget: %[4]s">
+<rect width="%[2]d" height="20" fill="%[5]s"/>
+<rect x="%[2]d" width="%[3]d" height="20" fill="%[6]s"/>
+<g fill="#fff" font-family="Verdana,DejaVu Sans,sans-serif" font-size="11" text-anchor="middle">
+<text x="%[7]d" y="14">budget</text>
+<text x="%[8]d" y="14">%[4]s</text>
+</g>
+</svg>
+`, width, labelWidth, valueWidth, value, labelColor, statusColor(p), labelWidth/2, labelWidth+valueWidth/2)
+	return err
+}
+
+// WriteBar writes an SVG with the balance bar above the elapsed time bar
+func WriteBar(w io.Writer, p Progress) error {
+	const width, barWidth = 240, 200
+	balance := barWidth * clamp(p.BalancePct, 0, 100) / 100
+	elapsed := barWidth * p.ElapsedPct / 100
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="40" role="img" aria-label="balance %[3]d%%, elapsed %[5]d%%">
+<g font-family="Verdana,DejaVu Sans,sans-serif" font-size="10" fill="%[8]s">
+<rect x="0" y="4" width="%[2]d" height="12" fill="%[9]s"/>
+<rect x="0" y="4" width="%[4]d" height="12" fill="%[7]s"/>
+<text x="%[10]d" y="14">%[3]d%%</text>
+<rect x="0" y="24" width="%[2]d" height="12" fill="%[9]s"/>
+<rect x="0" y="24" width="%[6]d" height="12" fill="%[11]s"/>
+<text x="%[10]d" y="34">%[5]d%%</text>
+</g>
+</svg>
+`, width, barWidth, p.BalancePct, balance, p.ElapsedPct, elapsed, statusColor(p), labelColor, trackColor, barWidth+6, elapsedColor)
+	return err
+}
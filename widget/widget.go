@@ -0,0 +1,173 @@
+// Package widget renders compact budget progress for home screen widgets and e-ink displays.
+package widget
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+// Built-in formats
+const (
+	FormatJSON  = "json"
+	FormatCSV   = "csv"
+	FormatBadge = "badge"
+	FormatBar   = "bar"
+)
+
+// Formats are the built-in formats
+var Formats = []string{FormatJSON, FormatCSV, FormatBadge, FormatBar}
+
+// MaxTemplateSize limits a size of a user defined template
+const MaxTemplateSize = 4 << 10
+
+// maxOutputSize limits an output of a user defined template
+const maxOutputSize = 64 << 10
+
+var nameRE = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// ErrOutputTooLarge is returned when a template output exceeds the limit
+var ErrOutputTooLarge = errors.New("template output is too large")
+
+// Progress is a compact budget status
+type Progress struct {
+	Balance        float64 `json:"balance"`
+	Budget         float64 `json:"budget"`
+	Deviation      float64 `json:"deviation"`
+	TodayAllowance float64 `json:"today_allowance"`
+	DaysLeft       float64 `json:"days_left"`
+	// BalancePct is the total balance in percents of the budget amount, 0 if there is no budget
+	BalancePct int `json:"balance_pct"`
+	// ElapsedPct is the elapsed time in percents of the budget period
+	ElapsedPct int   `json:"elapsed_pct"`
+	UpdatedAt  int64 `json:"updated_at"`
+}
+
+// FromStat returns progress of the statistics at the moment
+func FromStat(stat budget.Statistics, now time.Time) Progress {
+	p := Progress{
+		Balance:        finite(stat.TotalBalance),
+		Budget:         finite(stat.BudgetAmount),
+		Deviation:      finite(stat.BalanceDeviation),
+		TodayAllowance: finite(stat.TodayAllowance),
+		DaysLeft:       math.Max(finite(stat.BudgetDaysToExpiration), 0),
+		UpdatedAt:      now.Unix(),
+	}
+
+	if stat.BudgetAmount > 0 {
+		p.BalancePct = percent(stat.TotalBalance / stat.BudgetAmount)
+	}
+
+	total := stat.BudgetExpiresAt - stat.BudgetStartedAt
+	if total > 0 {
+		p.ElapsedPct = clamp(percent(float64(now.Unix()-stat.BudgetStartedAt)/float64(total)), 0, 100)
+	} else {
+		p.ElapsedPct = 100
+	}
+
+	return p
+}
+
+// IsBuiltin reports whether the format is built in
+func IsBuiltin(format string) bool {
+	for _, f := range Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidateName checks a name of a user defined format
+func ValidateName(name string) error {
+	if !nameRE.MatchString(name) {
+		return fmt.Errorf("format name must match %s", nameRE)
+	}
+	if IsBuiltin(name) {
+		return fmt.Errorf("%s is a built-in format", name)
+	}
+	return nil
+}
+
+// ParseTemplate parses a user defined text/template executed with Progress
+func ParseTemplate(text string) (*template.Template, error) {
+	if len(text) > MaxTemplateSize {
+		return nil, fmt.Errorf("template is larger than %d bytes", MaxTemplateSize)
+	}
+
+	return template.New("widget").Funcs(funcs).Option("missingkey=error").Parse(text)
+}
+
+// ExecuteTemplate executes a user defined template
+func ExecuteTemplate(w io.Writer, text string, p Progress) error {
+	t, err := ParseTemplate(text)
+	if err != nil {
+		return err
+	}
+
+	return t.Execute(&limitedWriter{w: w, left: maxOutputSize}, p)
+}
+
+// WriteCSV writes the progress as balance and elapsed percents
+func WriteCSV(w io.Writer, p Progress) error {
+	_, err := fmt.Fprintf(w, "balance,elapsed\n%d,%d\n", p.BalancePct, p.ElapsedPct)
+	return err
+}
+
+var funcs = template.FuncMap{
+	"round": func(v float64, prec int) float64 {
+		pow := math.Pow(10, float64(prec))
+		return math.Round(v*pow) / pow
+	},
+	"int": func(v float64) int64 {
+		return int64(math.Round(v))
+	},
+	"repeat": func(s string, n int) string {
+		return strings.Repeat(s, clamp(n, 0, 100))
+	},
+	"date": func(layout string, at int64) string {
+		return time.Unix(at, 0).Format(layout)
+	},
+}
+
+type limitedWriter struct {
+	w    io.Writer
+	left int
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if len(p) > l.left {
+		return 0, ErrOutputTooLarge
+	}
+	l.left -= len(p)
+	return l.w.Write(p)
+}
+
+// finite replaces NaN and infinities of degenerate budgets with 0
+func finite(v float64) float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0
+	}
+	return v
+}
+
+func percent(v float64) int {
+	return int(math.Round(finite(v) * 100))
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,83 @@
+package widget
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/unkeep/alfabooker/budget"
+)
+
+func TestFromStat(t *testing.T) {
+	now := time.Unix(1000, 0)
+
+	p := FromStat(budget.Statistics{
+		BudgetAmount:    1000,
+		TotalBalance:    600,
+		BudgetStartedAt: 0,
+		BudgetExpiresAt: 4000,
+	}, now)
+	if p.BalancePct != 60 || p.ElapsedPct != 25 {
+		t.Errorf("unexpected progress %+v", p)
+	}
+
+	// a zero budget must not divide by zero
+	p = FromStat(budget.Statistics{TotalBalance: 600, BudgetStartedAt: 1000, BudgetExpiresAt: 1000}, now)
+	if p.BalancePct != 0 || p.ElapsedPct != 100 {
+		t.Errorf("unexpected progress of a zero budget %+v", p)
+	}
+}
+
+func TestSVG(t *testing.T) {
+	p := Progress{BalancePct: 60, ElapsedPct: 25}
+
+	for name, write := range map[string]func(*bytes.Buffer, Progress) error{
+		FormatBadge: func(b *bytes.Buffer, p Progress) error { return WriteBadge(b, p) },
+		FormatBar:   func(b *bytes.Buffer, p Progress) error { return WriteBar(b, p) },
+	} {
+		var buf bytes.Buffer
+		if err := write(&buf, p); err != nil {
+			t.Fatal(err)
+		}
+		var svg struct {
+			XMLName xml.Name `xml:"svg"`
+		}
+		if err := xml.Unmarshal(buf.Bytes(), &svg); err != nil {
+			t.Errorf("%s is not a valid SVG: %s\n%s", name, err, buf.String())
+		}
+		if !strings.Contains(buf.String(), "60%") {
+			t.Errorf("%s does not show the balance: %s", name, buf.String())
+		}
+	}
+}
+
+func TestExecuteTemplate(t *testing.T) {
+	p := Progress{Balance: 612.345, BalancePct: 61, ElapsedPct: 25}
+
+	var buf bytes.Buffer
+	if err := ExecuteTemplate(&buf, `{{round .Balance 1}} {{.BalancePct}}/{{.ElapsedPct}}`, p); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "612.3 61/25" {
+		t.Errorf("unexpected output %q", buf.String())
+	}
+
+	if err := ExecuteTemplate(&buf, `{{.Unknown}}`, p); err == nil {
+		t.Error("unknown fields must fail")
+	}
+
+	err := ExecuteTemplate(&bytes.Buffer{}, `{{define "r"}}{{repeat "#" 100}}{{template "r" .}}{{end}}{{template "r" .}}`, p)
+	if err == nil || !strings.Contains(err.Error(), ErrOutputTooLarge.Error()) {
+		t.Errorf("unexpected error of a large output: %v", err)
+	}
+}
+
+func TestValidateName(t *testing.T) {
+	for name, valid := range map[string]bool{"eink": true, "kindle-2": true, "json": false, "Big": false, "": false} {
+		if err := ValidateName(name); (err == nil) != valid {
+			t.Errorf("ValidateName(%q) = %v", name, err)
+		}
+	}
+}